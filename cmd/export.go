@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gridprotocol/validator/core/export"
+	"github.com/gridprotocol/validator/core/store"
+	"github.com/gridprotocol/validator/core/types"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+)
+
+var exportCmd = &cli.Command{
+	Name:  "export",
+	Usage: "export cycles, proof results or ledger entries of a cycle range from the validator database",
+	Flags: append([]cli.Flag{
+		&cli.StringFlag{
+			Name:     "dataset",
+			Usage:    "records to export, e.g.(cycles, results, ledger)",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "format of the export, e.g.(csv, jsonl, parquet)",
+			Value: export.FormatCSV,
+		},
+		&cli.Int64Flag{
+			Name:  "from-cycle",
+			Usage: "first cycle to export",
+		},
+		&cli.Int64Flag{
+			Name:  "to-cycle",
+			Usage: "last cycle to export",
+		},
+		&cli.StringFlag{
+			Name:  "from",
+			Usage: "export the cycles started on or after this utc day, e.g.(2024-12-01)",
+		},
+		&cli.StringFlag{
+			Name:  "to",
+			Usage: "export the cycles started on or before this utc day, e.g.(2024-12-31)",
+		},
+		&cli.StringFlag{
+			Name:  "provider",
+			Usage: "only the results or ledger entries of this provider",
+		},
+		&cli.StringFlag{
+			Name:     "out",
+			Aliases:  []string{"o"},
+			Usage:    "file to write",
+			Required: true,
+		},
+	}, dbFlags...),
+	Action: func(ctx *cli.Context) error {
+		q := types.ExportQuery{
+			Dataset:   ctx.String("dataset"),
+			Format:    ctx.String("format"),
+			FromCycle: ctx.Int64("from-cycle"),
+			ToCycle:   ctx.Int64("to-cycle"),
+			Provider:  ctx.String("provider"),
+		}
+
+		var err error
+		q.From, err = parseDate(ctx.String("from"))
+		if err != nil {
+			return xerrors.Errorf("from: %w", err)
+		}
+		q.To, err = parseDate(ctx.String("to"))
+		if err != nil {
+			return xerrors.Errorf("to: %w", err)
+		}
+
+		err = export.Check(q)
+		if err != nil {
+			return err
+		}
+
+		db, err := openDB(ctx)
+		if err != nil {
+			return err
+		}
+		defer store.CloseDB(db)
+
+		// the tables must be of the schema this binary reads
+		m, err := store.NewMigrator(db, ctx.String("db-backend"))
+		if err != nil {
+			return err
+		}
+		err = m.Check()
+		if err != nil {
+			return err
+		}
+
+		path := ctx.String("out")
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+
+		filter := export.Filter(q)
+		err = export.Write(ctx.Context, store.NewExporter(db), f, q, filter)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			// a truncated export is not kept
+			os.Remove(path)
+			return err
+		}
+
+		fmt.Println("exported", q.Dataset, "to", path)
+		return nil
+	},
+}
+
+// utc day, zero if empty
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	return time.Parse(export.DateFormat, s)
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/gofrs/flock"
+	"github.com/mitchellh/go-homedir"
+	"golang.org/x/xerrors"
+)
+
+// held by the running validator, commands that replace its databases take it as well
+const lockFile = "validator.lock"
+
+// locks the data dir, fails when the validator or another command holds it
+func lockDataDir(dataDir string) (func(), error) {
+	dir, err := homedir.Expand(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(dir, 0750)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := flock.New(filepath.Join(dir, lockFile))
+	ok, err := lock.TryLock()
+	if err != nil {
+		return nil, xerrors.Errorf("lock %s: %w", lock.Path(), err)
+	}
+	if !ok {
+		return nil, xerrors.Errorf("%s is locked, stop the validator using %s first", lock.Path(), dir)
+	}
+
+	return func() { _ = lock.Unlock() }, nil
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/gridprotocol/validator/logs"
+
+	"github.com/urfave/cli/v2"
+)
+
+var logger = logs.Logger("grid cmd")
+
+var logFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:    "log-format",
+		Usage:   "log output format, e.g.(json, console)",
+		EnvVars: []string{"MEFS_LOG_FORMAT"},
+		Value:   logs.DefaultConfig().Format,
+	},
+	&cli.StringFlag{
+		Name:    "log-levels",
+		Usage:   "global and per logger levels, e.g.(info,gin=warn,grid validator=debug)",
+		EnvVars: []string{"MEFS_LOG_LEVELS", "MEFS_LOG_LEVEL"},
+	},
+	&cli.StringFlag{
+		Name:  "log-levels-file",
+		Usage: "file with log levels in the log-levels format, one entry per line, reloaded on SIGHUP",
+	},
+	&cli.BoolFlag{
+		Name:  "log-stdout",
+		Usage: "write logs to stdout, they can go to a file and syslog as well",
+		Value: logs.DefaultConfig().Stdout,
+	},
+	&cli.StringFlag{
+		Name:    "log-file",
+		Usage:   "write logs to this file, rotated by size",
+		EnvVars: []string{"MEFS_LOG_FILE"},
+	},
+	&cli.IntFlag{
+		Name:  "log-max-size",
+		Usage: "size of the log file in megabytes before it is rotated",
+		Value: logs.DefaultConfig().File.MaxSize,
+	},
+	&cli.IntFlag{
+		Name:  "log-max-backups",
+		Usage: "rotated log files to keep, 0 keeps all",
+		Value: logs.DefaultConfig().File.MaxBackups,
+	},
+	&cli.IntFlag{
+		Name:  "log-max-age",
+		Usage: "days to keep rotated log files, 0 keeps them regardless of age",
+		Value: logs.DefaultConfig().File.MaxAge,
+	},
+	&cli.BoolFlag{
+		Name:  "log-compress",
+		Usage: "gzip rotated log files",
+	},
+	&cli.StringFlag{
+		Name:  "log-syslog",
+		Usage: "send logs to a syslog server over udp, e.g.(127.0.0.1:514)",
+	},
+	&cli.StringFlag{
+		Name:  "log-syslog-tag",
+		Usage: "tag of syslog messages",
+		Value: logs.DefaultConfig().Syslog.Tag,
+	},
+	&cli.StringFlag{
+		Name:  "log-syslog-facility",
+		Usage: "facility of syslog messages, e.g.(daemon, local0)",
+		Value: logs.DefaultConfig().Syslog.Facility,
+	},
+	&cli.DurationFlag{
+		Name:  "log-sample-tick",
+		Usage: "period of sampling high-volume messages such as rejected proofs",
+		Value: logs.DefaultConfig().Sampling.Tick,
+	},
+	&cli.IntFlag{
+		Name:  "log-sample-first",
+		Usage: "repeats of a high-volume message logged in each period before sampling, 0 disables sampling",
+		Value: logs.DefaultConfig().Sampling.First,
+	},
+	&cli.IntFlag{
+		Name:  "log-sample-thereafter",
+		Usage: "log every nth repeat of a high-volume message after the first ones, 0 drops them",
+		Value: logs.DefaultConfig().Sampling.Thereafter,
+	},
+}
+
+// open log outputs and apply levels from flags, the levels file wins over the levels flag.
+// the returned func flushes and closes the outputs
+func setupLogs(ctx *cli.Context) (func() error, error) {
+	cfg := logs.DefaultConfig()
+	cfg.Format = ctx.String("log-format")
+	cfg.Stdout = ctx.Bool("log-stdout")
+	cfg.File.Path = ctx.String("log-file")
+	cfg.File.MaxSize = ctx.Int("log-max-size")
+	cfg.File.MaxBackups = ctx.Int("log-max-backups")
+	cfg.File.MaxAge = ctx.Int("log-max-age")
+	cfg.File.Compress = ctx.Bool("log-compress")
+	cfg.Syslog.Address = ctx.String("log-syslog")
+	cfg.Syslog.Tag = ctx.String("log-syslog-tag")
+	cfg.Syslog.Facility = ctx.String("log-syslog-facility")
+	cfg.Sampling.Tick = ctx.Duration("log-sample-tick")
+	cfg.Sampling.First = ctx.Int("log-sample-first")
+	cfg.Sampling.Thereafter = ctx.Int("log-sample-thereafter")
+
+	err := applyLogLevels(ctx.String("log-levels"), ctx.String("log-levels-file"))
+	if err != nil {
+		return nil, err
+	}
+
+	return logs.Init(cfg)
+}
+
+func applyLogLevels(spec, file string) error {
+	if spec != "" {
+		err := logs.ApplyLevels(spec)
+		if err != nil {
+			return err
+		}
+	}
+
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		return logs.ApplyLevels(string(data))
+	}
+
+	return nil
+}
+
+// apply the log levels again on SIGHUP, dropping the ones changed at runtime
+func reloadLogLevels(ctx context.Context, spec, file string) error {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hup:
+		}
+
+		err := applyLogLevels(spec, file)
+		if err != nil {
+			logger.Errorf("reload log levels: %s", err)
+			continue
+		}
+		logger.Infof("log levels reloaded, global level %s", logs.GetLogLevel())
+	}
+}
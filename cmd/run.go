@@ -5,10 +5,20 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"os"
 	"os/signal"
+	"slices"
 	"syscall"
-
+	"time"
+
+	"github.com/gridprotocol/validator/core/admin"
+	"github.com/gridprotocol/validator/core/backup"
+	"github.com/gridprotocol/validator/core/certs"
+	"github.com/gridprotocol/validator/core/health"
+	"github.com/gridprotocol/validator/core/metrics"
+	"github.com/gridprotocol/validator/core/middleware"
+	"github.com/gridprotocol/validator/core/store"
+	"github.com/gridprotocol/validator/core/syncer"
+	"github.com/gridprotocol/validator/core/tracing"
 	"github.com/gridprotocol/validator/core/validator"
 
 	"github.com/gridprotocol/dumper/database"
@@ -18,6 +28,8 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/gin-gonic/gin"
 	"github.com/urfave/cli/v2"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/xerrors"
 
 	"github.com/grid/contracts/eth"
 )
@@ -30,6 +42,9 @@ var ValidatorCmd = &cli.Command{
 	Subcommands: []*cli.Command{
 		// validatorNodeRunCmd,
 		runCmd,
+		dbCmd,
+		exportCmd,
+		syncCmd,
 	},
 }
 
@@ -37,7 +52,7 @@ var ValidatorCmd = &cli.Command{
 var runCmd = &cli.Command{
 	Name:  "run",
 	Usage: "run meeda store node",
-	Flags: []cli.Flag{
+	Flags: slices.Concat([]cli.Flag{
 		&cli.StringFlag{
 			Name:    "endpoint",
 			Aliases: []string{"e"},
@@ -49,17 +64,221 @@ var runCmd = &cli.Command{
 			Usage: "input your private key",
 			Value: "5087077ba322c5bd02f95ed8b50ff9251b8f1d165455d0688c75f5d4740a19f4", // test validator sk
 		},
+		chainFlag,
 		&cli.StringFlag{
-			Name:  "chain",
-			Usage: "input chain name, e.g.(dev)",
-			Value: "dev",
+			Name:  "recover-policy",
+			Usage: "how to settle cycles missed during downtime, e.g.(neutral, reward-only, skip)",
+			Value: string(validator.RecoverNeutral),
 		},
-	},
+		&cli.Int64Flag{
+			Name:  "max-recover-cycles",
+			Usage: "max number of missed cycles settled at startup, 0 means no limit",
+			Value: validator.DefaultConfig().MaxRecoverCycles,
+		},
+		&cli.BoolFlag{
+			Name:  "drain",
+			Usage: "settle the current cycle before exit, without it an interrupted cycle is left to recovery",
+			Value: validator.DefaultConfig().Drain,
+		},
+		&cli.DurationFlag{
+			Name:  "shutdown-timeout",
+			Usage: "max time to wait for services to stop on exit",
+			Value: 30 * time.Second,
+		},
+		&cli.IntFlag{
+			Name:  "proof-intake",
+			Usage: "max proofs verified at the same time, a batch counts each of its proofs, more are rejected with 503",
+			Value: validator.DefaultConfig().IntakeSize,
+		},
+		&cli.Uint64Flag{
+			Name:  "max-sync-lag",
+			Usage: "max blocks the db may fall behind chain head before it is reported stale",
+			Value: syncer.DefaultConfig().MaxLag,
+		},
+		&cli.Float64Flag{
+			Name:  "rate-ip",
+			Usage: "requests per second allowed from one client ip, 0 means no limit",
+			Value: middleware.DefaultRateLimitConfig().IPRate,
+		},
+		&cli.IntFlag{
+			Name:  "rate-ip-burst",
+			Usage: "requests one client ip may send at once",
+			Value: middleware.DefaultRateLimitConfig().IPBurst,
+		},
+		&cli.Float64Flag{
+			Name:  "rate-provider",
+			Usage: "proof submissions per second allowed for one provider, 0 means no limit",
+			Value: middleware.DefaultRateLimitConfig().ProviderRate,
+		},
+		&cli.IntFlag{
+			Name:  "rate-provider-burst",
+			Usage: "proof submissions one provider may send at once",
+			Value: middleware.DefaultRateLimitConfig().ProviderBurst,
+		},
+		&cli.Int64Flag{
+			Name:  "max-body-size",
+			Usage: "max bytes of a request body, 0 means no limit",
+			Value: middleware.DefaultRateLimitConfig().MaxBodySize,
+		},
+		&cli.IntFlag{
+			Name:  "ban-threshold",
+			Usage: "invalid proofs within ban-window before the client ip is banned, 0 disables bans",
+			Value: middleware.DefaultRateLimitConfig().BanThreshold,
+		},
+		&cli.DurationFlag{
+			Name:  "ban-window",
+			Usage: "window in which invalid proofs are counted",
+			Value: middleware.DefaultRateLimitConfig().BanWindow,
+		},
+		&cli.DurationFlag{
+			Name:  "ban-duration",
+			Usage: "how long a ban lasts",
+			Value: middleware.DefaultRateLimitConfig().BanDuration,
+		},
+		&cli.StringFlag{
+			Name:  "tls-cert",
+			Usage: "certificate file, serve https when set",
+		},
+		&cli.StringFlag{
+			Name:  "tls-key",
+			Usage: "private key file of tls-cert",
+		},
+		&cli.StringFlag{
+			Name:  "tls-client-ca",
+			Usage: "ca bundle to verify client certificates, privileged routes require one when set",
+		},
+		&cli.DurationFlag{
+			Name:  "tls-reload-interval",
+			Usage: "interval of checking tls files for changes",
+			Value: certs.DefaultReloadInterval,
+		},
+		&cli.StringFlag{
+			Name:  "admin-token",
+			Usage: "bearer token of the admin api, the api is served under /admin when set",
+		},
+		&cli.StringFlag{
+			Name:  "admin-endpoint",
+			Usage: "serve the admin api on its own listener instead, e.g.(127.0.0.1:8082), requires admin-token",
+		},
+		&cli.StringFlag{
+			Name:  "trace-exporter",
+			Usage: "export tracing spans, e.g.(stdout, otlp), none when empty",
+		},
+		&cli.StringFlag{
+			Name:  "trace-endpoint",
+			Usage: "host:port of the otlp http collector",
+			Value: tracing.DefaultConfig().Endpoint,
+		},
+		&cli.BoolFlag{
+			Name:  "trace-insecure",
+			Usage: "send spans to the collector over plain http",
+			Value: tracing.DefaultConfig().Insecure,
+		},
+		&cli.Float64Flag{
+			Name:  "trace-sample-ratio",
+			Usage: "fraction of requests and cycles traced",
+			Value: tracing.DefaultConfig().SampleRatio,
+		},
+		&cli.BoolFlag{
+			Name:  "db-auto-migrate",
+			Usage: "apply pending db migrations at startup, otherwise refuse to start until validator db migrate",
+			Value: true,
+		},
+		backupDirFlag,
+		&cli.DurationFlag{
+			Name:  "backup-interval",
+			Usage: "take and verify a snapshot of the sqlite databases every interval, none when 0",
+		},
+		&cli.IntFlag{
+			Name:  "backup-keep",
+			Usage: "snapshots kept by the scheduled backup, all when 0",
+			Value: 7,
+		},
+		&cli.DurationFlag{
+			Name:  "db-import-interval",
+			Usage: "interval of importing orders and profits from the dumper into postgres",
+			Value: time.Minute,
+		},
+		&cli.StringSliceFlag{
+			Name:  "trusted-proxies",
+			Usage: "proxies whose X-Forwarded-For is trusted for the client ip, e.g.(127.0.0.1,10.0.0.0/8)",
+		},
+	}, logFlags, dbFlags),
 	Action: func(ctx *cli.Context) error {
+		closeLogs, err := setupLogs(ctx)
+		if err != nil {
+			return err
+		}
+		defer closeLogs()
+
+		traceCfg := tracing.DefaultConfig()
+		traceCfg.Exporter = ctx.String("trace-exporter")
+		traceCfg.Endpoint = ctx.String("trace-endpoint")
+		traceCfg.Insecure = ctx.Bool("trace-insecure")
+		traceCfg.SampleRatio = ctx.Float64("trace-sample-ratio")
+
+		shutdownTracing, err := tracing.Init(ctx.Context, traceCfg)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = shutdownTracing(flushCtx)
+		}()
+
 		endPoint := ctx.String("endpoint")
 		sk := ctx.String("sk")
 		chain := ctx.String("chain")
 
+		recoverPolicy, err := validator.ParseRecoverPolicy(ctx.String("recover-policy"))
+		if err != nil {
+			return err
+		}
+
+		cfg := validator.DefaultConfig()
+		cfg.RecoverPolicy = recoverPolicy
+		cfg.MaxRecoverCycles = ctx.Int64("max-recover-cycles")
+		cfg.Drain = ctx.Bool("drain")
+		cfg.IntakeSize = ctx.Int("proof-intake")
+		if cfg.IntakeSize < 1 {
+			return xerrors.Errorf("proof-intake must be at least 1, got %d", cfg.IntakeSize)
+		}
+
+		shutdownTimeout := ctx.Duration("shutdown-timeout")
+
+		syncCfg := syncer.DefaultConfig()
+		syncCfg.MaxLag = ctx.Uint64("max-sync-lag")
+
+		serverCfg := ServerConfig{
+			Endpoint:       endPoint,
+			RateLimit:      middleware.DefaultRateLimitConfig(),
+			TrustedProxies: ctx.StringSlice("trusted-proxies"),
+			AdminToken:     ctx.String("admin-token"),
+			AdminEndpoint:  ctx.String("admin-endpoint"),
+		}
+		if serverCfg.AdminEndpoint != "" && serverCfg.AdminToken == "" {
+			return xerrors.New("admin-endpoint requires admin-token, the admin api is never served without it")
+		}
+		serverCfg.RateLimit.IPRate = ctx.Float64("rate-ip")
+		serverCfg.RateLimit.IPBurst = ctx.Int("rate-ip-burst")
+		serverCfg.RateLimit.ProviderRate = ctx.Float64("rate-provider")
+		serverCfg.RateLimit.ProviderBurst = ctx.Int("rate-provider-burst")
+		serverCfg.RateLimit.MaxBodySize = ctx.Int64("max-body-size")
+		serverCfg.RateLimit.BanThreshold = ctx.Int("ban-threshold")
+		serverCfg.RateLimit.BanWindow = ctx.Duration("ban-window")
+		serverCfg.RateLimit.BanDuration = ctx.Duration("ban-duration")
+
+		// reload certificates on change
+		var reloader *certs.Reloader
+		if ctx.String("tls-cert") != "" || ctx.String("tls-client-ca") != "" {
+			reloader, err = certs.NewReloader(ctx.String("tls-cert"), ctx.String("tls-key"), ctx.String("tls-client-ca"))
+			if err != nil {
+				return err
+			}
+			serverCfg.TLS = reloader
+		}
+
 		privateKey, err := crypto.HexToECDSA(sk)
 		if err != nil {
 			privateKey, err = crypto.GenerateKey()
@@ -68,86 +287,308 @@ var runCmd = &cli.Command{
 			}
 		}
 
-		err = database.InitDatabase("~/grid")
+		dataDir := ctx.String("data-dir")
+
+		// sync and restore refuse to replace the databases while it is held
+		unlock, err := lockDataDir(dataDir)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		err = database.InitDatabase(dataDir)
+		if err != nil {
+			return err
+		}
+
+		// the synced block is kept for the dumper db of this host
+		dumperName, err := store.DumperName(dataDir)
 		if err != nil {
 			return err
 		}
 
-		// contract address
-		registryAddress := common.HexToAddress("0x10fd5Eb0A59398796aA6C368CF0562135C3e4c32")
-		marketAddress := common.HexToAddress("0xd43241c35E49158B61aD5c061b2d050D276f9E94")
+		// validator owned tables
+		var db validator.Store
+		var pg *store.PostgresStore
+		switch ctx.String("db-backend") {
+		case store.BackendSQLite:
+			err = store.InitStore(dataDir, ctx.Bool("db-auto-migrate"))
+			if err != nil {
+				return err
+			}
+			dumperStore := store.NewDumperStore()
+			db, syncCfg.Blocks = dumperStore, dumperStore.SyncedBlocks(dumperName)
+		case store.BackendPostgres:
+			pg, err = store.OpenPostgres(ctx.String("db-dsn"), ctx.Bool("db-auto-migrate"))
+			if err != nil {
+				return err
+			}
+			defer pg.Close()
+			db, syncCfg.Blocks = pg, pg.SyncedBlocks(dumperName)
+		default:
+			return xerrors.Errorf("unknown db backend %q, expect sqlite or postgres", ctx.String("db-backend"))
+		}
 
 		fmt.Println("registry: ", registryAddress)
 		fmt.Println("market: ", marketAddress)
 
-		// new dumper
-		dumper, err := dumper.NewGRIDDumper(getEndpointByChain(chain), registryAddress, marketAddress)
+		// restart the chain subscription when it drops
+		supervisor, err := newSupervisor(chain, syncCfg)
 		if err != nil {
 			return err
 		}
 
 		// generate db
-		err = dumper.DumpGRID()
+		err = supervisor.Dump(ctx.Context)
 		if err != nil {
 			return err
 		}
-		// sync db with chain
-		go dumper.SubscribeGRID(context.TODO())
+
+		if pg != nil {
+			err = pg.Import(ctx.Context)
+			if err != nil {
+				return err
+			}
+		} else {
+			// the profits of the last cycle are written after it is recorded, the validator may have stopped in between
+			err = store.NewDumperStore().ResumeSettlement()
+			if err != nil {
+				return err
+			}
+		}
 
 		// new validator
-		validator, err := validator.NewGRIDValidator(chain, privateKey)
+		validator, err := validator.NewGRIDValidator(chain, privateKey, db, cfg)
 		if err != nil {
 			return err
 		}
-		// validate all nodes every 2 hours
-		go validator.Start(context.TODO())
 
 		// new validator server
-		server, err := NewValidatorServer(validator, endPoint)
+		server, err := NewValidatorServer(validator, supervisor, serverCfg)
 		if err != nil {
 			return err
 		}
 
+		// admin api on its own listener
+		var adminServer *http.Server
+		if serverCfg.AdminEndpoint != "" {
+			adminServer = NewAdminServer(validator, supervisor, serverCfg)
+		}
+
+		// canceled on SIGINT/SIGTERM or when any service fails
+		sigCtx, stop := signal.NotifyContext(ctx.Context, syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+		g, gctx := errgroup.WithContext(sigCtx)
+
+		// reload log levels on SIGHUP
+		g.Go(func() error {
+			return reloadLogLevels(gctx, ctx.String("log-levels"), ctx.String("log-levels-file"))
+		})
+
+		// sync db with chain
+		g.Go(func() error {
+			return supervisor.Run(gctx)
+		})
+
+		// validate all nodes every cycle
+		g.Go(func() error {
+			return validator.Start(gctx)
+		})
+
+		if reloader != nil {
+			g.Go(func() error {
+				return reloader.Run(gctx, ctx.Duration("tls-reload-interval"))
+			})
+		}
+
+		// scheduled snapshots
+		if ctx.Duration("backup-interval") > 0 {
+			err = requireSQLite(ctx)
+			if err != nil {
+				return err
+			}
+
+			backupCfg := backup.Config{
+				DataDir:  dataDir,
+				Dir:      backupDir(ctx),
+				Interval: ctx.Duration("backup-interval"),
+				Keep:     ctx.Int("backup-keep"),
+			}
+			g.Go(func() error {
+				return backup.Run(gctx, backupCfg)
+			})
+		}
+
+		// keep postgres up to date with the dumper
+		if pg != nil {
+			g.Go(func() error {
+				return pg.Run(gctx, ctx.Duration("db-import-interval"))
+			})
+		}
+
 		// start server listen
-		go func() {
-			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-				log.Fatalf("listen: %s\n", err)
+		g.Go(func() error {
+			var err error
+			if reloader != nil {
+				err = server.ListenAndServeTLS("", "")
+			} else {
+				err = server.ListenAndServe()
+			}
+			if err != nil && err != http.ErrServerClosed {
+				return xerrors.Errorf("listen: %w", err)
 			}
+			return nil
+		})
+
+		if adminServer != nil {
+			g.Go(func() error {
+				err := adminServer.ListenAndServe()
+				if err != nil && err != http.ErrServerClosed {
+					return xerrors.Errorf("admin listen: %w", err)
+				}
+				return nil
+			})
+		}
+
+		// keep accepting proofs until the draining cycle is settled, then shut down server
+		g.Go(func() error {
+			<-gctx.Done()
+			<-validator.Stopped()
+
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+			if adminServer != nil {
+				err := adminServer.Shutdown(shutdownCtx)
+				if err != nil {
+					return err
+				}
+			}
+			return server.Shutdown(shutdownCtx)
+		})
+
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- g.Wait()
 		}()
 
-		quit := make(chan os.Signal, 1)
-		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-		<-quit
+		select {
+		case err := <-errCh:
+			return err
+		case <-gctx.Done():
+		}
+
 		log.Println("Shutting down server...")
 
-		if err := server.Shutdown(context.TODO()); err != nil {
-			log.Fatal("Server forced to shutdown: ", err)
+		select {
+		case err := <-errCh:
+			if err != nil {
+				return err
+			}
+		case <-time.After(shutdownTimeout):
+			return xerrors.Errorf("shutdown timed out after %s", shutdownTimeout)
 		}
 
-		validator.Stop()
 		log.Println("Server exiting")
 
 		return nil
 	},
 }
 
+// options of the validator http server
+type ServerConfig struct {
+	Endpoint  string
+	RateLimit middleware.RateLimitConfig
+	// proxies trusted to report the client ip, none by default
+	TrustedProxies []string
+	// serve https when set, with mutual tls on privileged routes if it has a client ca
+	TLS *certs.Reloader
+	// admin api is served under /admin with the token, or on its own endpoint
+	AdminToken    string
+	AdminEndpoint string
+}
+
 // new gin server, register route
-func NewValidatorServer(validator *validator.GRIDValidator, endpoint string) (*http.Server, error) {
+func NewValidatorServer(validator *validator.GRIDValidator, supervisor *syncer.Supervisor, cfg ServerConfig) (*http.Server, error) {
 	gin.SetMode(gin.ReleaseMode)
-	router := gin.Default()
+	router := gin.New()
+	router.Use(gin.Recovery(), middleware.AccessLog())
+
+	err := router.SetTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
 
 	router.MaxMultipartMemory = 8 << 20 // 8 MiB
+	router.Use(metrics.Middleware(), middleware.RequestID(), middleware.Tracing(), middleware.RequestLogger(), middleware.ErrorHandler())
 	router.GET("/", func(c *gin.Context) {
 		c.String(http.StatusOK, "Welcome GRID Validator Node")
 	})
 
-	// register all route
-	validator.LoadValidatorModule(router.Group("/v1"))
+	// node status
+	checker := health.NewChecker(validator, supervisor)
+	router.GET("/healthz", checker.LivenessHandler)
+	router.GET("/readyz", checker.ReadinessHandler)
+	router.GET("/healthz/sync", supervisor.StatusHandler)
+	router.GET("/metrics", metrics.Handler())
+
+	// register all route, the public api is rate limited
+	limiter := middleware.NewRateLimiter(cfg.RateLimit)
+	var privileged []gin.HandlerFunc
+	if cfg.TLS != nil && cfg.TLS.MutualTLS() {
+		privileged = append(privileged, middleware.RequireClientCert())
+	}
+	validator.LoadValidatorModule(router.Group("/v1", limiter.Middleware()), privileged...)
+
+	// operator api, never without a token on the public listener
+	if cfg.AdminToken != "" && cfg.AdminEndpoint == "" {
+		admin.NewAdmin(validator, supervisor).LoadAdminModule(router.Group("/admin", middleware.BearerToken(cfg.AdminToken)))
+	}
+
+	server := &http.Server{
+		Addr:    cfg.Endpoint,
+		Handler: router,
+	}
+	if cfg.TLS != nil {
+		server.TLSConfig = cfg.TLS.TLSConfig()
+	}
+
+	return server, nil
+}
+
+// gin server of the admin api only, guarded by the token
+func NewAdminServer(validator *validator.GRIDValidator, supervisor *syncer.Supervisor, cfg ServerConfig) *http.Server {
+	router := gin.New()
+	router.Use(gin.Recovery(), middleware.AccessLog(), middleware.RequestID(), middleware.ErrorHandler())
+
+	rg := router.Group("/admin", middleware.BearerToken(cfg.AdminToken))
+	admin.NewAdmin(validator, supervisor).LoadAdminModule(rg)
 
 	return &http.Server{
-		Addr:    endpoint,
+		Addr:    cfg.AdminEndpoint,
 		Handler: router,
-	}, nil
+	}
+}
+
+var chainFlag = &cli.StringFlag{
+	Name:  "chain",
+	Usage: "input chain name, e.g.(dev)",
+	Value: "dev",
+}
+
+// contract address
+var (
+	registryAddress = common.HexToAddress("0x10fd5Eb0A59398796aA6C368CF0562135C3e4c32")
+	marketAddress   = common.HexToAddress("0xd43241c35E49158B61aD5c061b2d050D276f9E94")
+)
+
+// dumper of the contracts on chain, driven by the supervisor
+func newSupervisor(chain string, cfg syncer.Config) (*syncer.Supervisor, error) {
+	dumper, err := dumper.NewGRIDDumper(getEndpointByChain(chain), registryAddress, marketAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	return syncer.NewSupervisor(dumper, getEndpointByChain(chain), cfg)
 }
 
 func getEndpointByChain(chain string) string {
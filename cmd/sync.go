@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/gridprotocol/validator/core/backup"
+	"github.com/gridprotocol/validator/core/store"
+	"github.com/gridprotocol/validator/core/syncer"
+	"github.com/gridprotocol/validator/logs"
+
+	"github.com/gridprotocol/dumper/database"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+)
+
+var syncCmd = &cli.Command{
+	Name:  "sync",
+	Usage: "rebuild the dumper db from chain with the validator stopped, and report how the local db differed from chain",
+	Flags: append([]cli.Flag{
+		chainFlag,
+		&cli.BoolFlag{
+			Name:  "verify-only",
+			Usage: "only report how the local db differs from chain, fails if it differs. the validator may be running",
+		},
+	}, dbFlags...),
+	Action: func(ctx *cli.Context) error {
+		dataDir, err := homedir.Expand(ctx.String("data-dir"))
+		if err != nil {
+			return err
+		}
+
+		// the validator writes the databases a sync replaces
+		if !ctx.Bool("verify-only") {
+			unlock, err := lockDataDir(dataDir)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+		}
+
+		err = database.InitDatabase(dataDir)
+		if err != nil {
+			return err
+		}
+
+		// orders and profits the validator uses, and its settled changes to the profits
+		var local syncer.StateReader
+		var pg *store.PostgresStore
+		var settled map[string]*store.Settlement
+		switch ctx.String("db-backend") {
+		case store.BackendSQLite:
+			err = store.InitStore(dataDir, false)
+			if err != nil {
+				return err
+			}
+			dumperStore := store.NewDumperStore()
+			local = dumperStore
+			settled, err = dumperStore.SumLedgerByProvider(ctx.Context)
+		case store.BackendPostgres:
+			pg, err = store.OpenPostgres(ctx.String("db-dsn"), false)
+			if err != nil {
+				return err
+			}
+			defer pg.Close()
+			local = pg
+			settled, err = pg.SumLedgerByProvider(ctx.Context)
+		default:
+			return xerrors.Errorf("unknown db backend %q, expect sqlite or postgres", ctx.String("db-backend"))
+		}
+		if err != nil {
+			return xerrors.Errorf("sum the ledger: %w", err)
+		}
+
+		before, err := syncer.ReadState(local)
+		if err != nil {
+			return err
+		}
+
+		supervisor, err := newSupervisor(ctx.String("chain"), syncer.DefaultConfig())
+		if err != nil {
+			return err
+		}
+
+		// the chain data is dumped into a new database next to the local one
+		tmp := filepath.Join(dataDir, ".sync")
+		err = os.RemoveAll(tmp)
+		if err != nil {
+			return err
+		}
+		err = os.MkdirAll(tmp, 0750)
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmp)
+
+		err = database.InitDatabase(tmp)
+		if err != nil {
+			return err
+		}
+		err = supervisor.Dump(ctx.Context)
+		if err != nil {
+			return err
+		}
+
+		// chain has none of the settled changes, they are carried over from the ledger
+		// so that only the chain data is compared and rebuilt
+		err = applySettled(store.NewDumperStore(), settled)
+		if err != nil {
+			return err
+		}
+
+		// read from the new database
+		chain, err := syncer.ReadState(store.NewDumperStore())
+		if err != nil {
+			return err
+		}
+
+		diffs := syncer.Compare(before, chain)
+		printDiscrepancies(diffs)
+
+		if ctx.Bool("verify-only") {
+			if len(diffs) > 0 {
+				return xerrors.Errorf("local db differs from chain in %d records", len(diffs))
+			}
+			return nil
+		}
+
+		files, err := backup.Restore(tmp, dataDir)
+		if err != nil {
+			return xerrors.Errorf("replace the dumper db: %w", err)
+		}
+		for _, file := range files {
+			fmt.Println("rebuilt", file, "the previous one is kept with the .pre-restore suffix")
+		}
+
+		if pg != nil {
+			err = database.InitDatabase(dataDir)
+			if err != nil {
+				return err
+			}
+
+			err = pg.Import(ctx.Context)
+			if err != nil {
+				return err
+			}
+			fmt.Println("imported into postgres")
+		}
+
+		return nil
+	},
+}
+
+// add the settled changes of the ledger to the profits of the dumper database in use
+func applySettled(db *store.DumperStore, settled map[string]*store.Settlement) error {
+	for provider, settlement := range settled {
+		profit, err := db.GetProfit(provider)
+		if errors.Is(err, logs.ErrNotExist) {
+			logger.Warnf("profit of %s is not on chain, its settled changes are only in the ledger", provider)
+			continue
+		}
+		if err != nil {
+			return xerrors.Errorf("profit of %s: %w", provider, err)
+		}
+
+		settlement.Apply(&profit)
+		err = db.UpdateProfit(profit)
+		if err != nil {
+			return xerrors.Errorf("settle profit of %s: %w", provider, err)
+		}
+	}
+
+	return nil
+}
+
+func printDiscrepancies(diffs []syncer.Discrepancy) {
+	if len(diffs) == 0 {
+		fmt.Println("local db matches chain")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tRECORD\tFIELD\tLOCAL\tCHAIN")
+	for _, d := range diffs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", d.Kind, d.Record, d.Field, d.Local, d.Chain)
+	}
+	w.Flush()
+}
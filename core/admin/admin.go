@@ -0,0 +1,42 @@
+package admin
+
+import (
+	"github.com/gridprotocol/validator/core/syncer"
+	"github.com/gridprotocol/validator/core/validator"
+	"github.com/gridprotocol/validator/logs"
+
+	"github.com/gin-gonic/gin"
+)
+
+var logger = logs.Logger("grid admin")
+
+// operator controls of a running validator
+type Admin struct {
+	validator  *validator.GRIDValidator
+	supervisor *syncer.Supervisor
+}
+
+func NewAdmin(validator *validator.GRIDValidator, supervisor *syncer.Supervisor) *Admin {
+	return &Admin{
+		validator:  validator,
+		supervisor: supervisor,
+	}
+}
+
+// register admin routes, the caller guards the group
+func (a *Admin) LoadAdminModule(rg *gin.RouterGroup) {
+	rg.GET("/log/level", a.GetLogLevelHandler)
+	rg.PUT("/log/level", a.SetLogLevelHandler)
+
+	rg.GET("/challenge", a.GetChallengeHandler)
+	rg.POST("/challenge/pause", a.PauseHandler)
+	rg.POST("/challenge/resume", a.ResumeHandler)
+
+	rg.POST("/cycle/trigger", a.TriggerCycleHandler)
+	rg.GET("/cycle/results", a.GetCycleResultsHandler)
+
+	rg.POST("/sync", a.ResyncHandler)
+
+	rg.GET("/difficulty", a.GetDifficultiesHandler)
+	rg.PUT("/difficulty", a.SetDifficultyHandler)
+}
@@ -0,0 +1,107 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/gridprotocol/validator/core/middleware"
+	"github.com/gridprotocol/validator/core/types"
+	"github.com/gridprotocol/validator/logs"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (a *Admin) GetLogLevelHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, logLevels())
+}
+
+// set the global level, or the level of one named logger
+func (a *Admin) SetLogLevelHandler(c *gin.Context) {
+	var req types.LogLevel
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		middleware.AbortWithError(c, logs.BadRequest{Message: err.Error()})
+		return
+	}
+
+	if req.Name == "" {
+		err = logs.SetLogLevel(req.Level)
+	} else {
+		err = logs.SetNamedLogLevel(req.Name, req.Level)
+	}
+	if err != nil {
+		middleware.AbortWithError(c, logs.BadRequest{Message: err.Error()})
+		return
+	}
+
+	logger.Infof("log level of %q set to %q", req.Name, req.Level)
+	c.JSON(http.StatusOK, logLevels())
+}
+
+func logLevels() types.LogLevels {
+	return types.LogLevels{
+		Level: logs.GetLogLevel(),
+		Names: logs.GetNamedLogLevels(),
+	}
+}
+
+func (a *Admin) GetChallengeHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, types.ChallengeState{Paused: a.validator.Paused()})
+}
+
+func (a *Admin) PauseHandler(c *gin.Context) {
+	a.validator.Pause()
+	c.JSON(http.StatusOK, types.ChallengeState{Paused: true})
+}
+
+func (a *Admin) ResumeHandler(c *gin.Context) {
+	a.validator.Resume()
+	c.JSON(http.StatusOK, types.ChallengeState{Paused: false})
+}
+
+// start the next cycle now
+func (a *Admin) TriggerCycleHandler(c *gin.Context) {
+	err := a.validator.TriggerCycle()
+	if err != nil {
+		middleware.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, "cycle triggered")
+}
+
+func (a *Admin) GetCycleResultsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, a.validator.CycleResults())
+}
+
+// dump the chain into db again and restart the subscription
+func (a *Admin) ResyncHandler(c *gin.Context) {
+	err := a.supervisor.Resync()
+	if err != nil {
+		middleware.AbortWithError(c, logs.Unavailable{Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, "resync started")
+}
+
+func (a *Admin) GetDifficultiesHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, a.validator.Difficulties())
+}
+
+// override the difficulty of a node, 0 restores the default
+func (a *Admin) SetDifficultyHandler(c *gin.Context) {
+	var req types.NodeDifficulty
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		middleware.AbortWithError(c, logs.BadRequest{Message: err.Error()})
+		return
+	}
+
+	err = a.validator.SetDifficulty(req.NodeID, req.Difficulty)
+	if err != nil {
+		middleware.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, req)
+}
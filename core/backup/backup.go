@@ -0,0 +1,435 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gridprotocol/validator/logs"
+
+	"github.com/mitchellh/go-homedir"
+	"golang.org/x/xerrors"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+var logger = logs.Logger("grid backup")
+
+// snapshots are directories named by their utc time in nanoseconds, so they sort by age
+// and two snapshots of the same second get their own directories
+const (
+	snapshotPrefix = "snapshot-"
+	timeFormat     = "20060102T150405.000000000Z"
+)
+
+// the dumper opens its database with the default busy timeout of the sqlite driver, 5s,
+// its writes fail when they are paused longer, so a snapshot pausing them gives up before
+const maxPause = 4 * time.Second
+
+// kept when a database is replaced by restore
+const preRestoreSuffix = ".pre-restore"
+
+var sqliteHeader = []byte("SQLite format 3\x00")
+
+type Config struct {
+	// directory of the dumper database and validator.db
+	DataDir string
+	// directory the snapshots are written to
+	Dir string
+	// time between snapshots, no snapshots when 0
+	Interval time.Duration
+	// snapshots to keep, all are kept when 0
+	Keep int
+}
+
+// snapshot every sqlite database in dataDir into a new directory in dir, and return its path.
+// writes to all databases are paused while they are copied with VACUUM INTO, so the snapshot holds
+// the databases at one moment, e.g. a settled cycle with its profits. it is checked before it is published
+func Snapshot(ctx context.Context, dataDir, dir string) (string, error) {
+	dataDir, err := homedir.Expand(dataDir)
+	if err != nil {
+		return "", err
+	}
+	dir, err = homedir.Expand(dir)
+	if err != nil {
+		return "", err
+	}
+
+	files, err := listDatabases(dataDir)
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		return "", xerrors.Errorf("no sqlite database in %s", dataDir)
+	}
+
+	err = os.MkdirAll(dir, 0750)
+	if err != nil {
+		return "", err
+	}
+	name := snapshotPrefix + time.Now().UTC().Format(timeFormat)
+	final := filepath.Join(dir, name)
+	tmp := final + ".tmp"
+	// never shared with another snapshot
+	err = os.Mkdir(tmp, 0750)
+	if err != nil {
+		return "", err
+	}
+	// left only if the snapshot failed
+	defer os.RemoveAll(tmp)
+
+	err = copyPaused(ctx, dataDir, tmp, files)
+	if err != nil {
+		return "", err
+	}
+
+	err = Verify(tmp)
+	if err != nil {
+		return "", err
+	}
+
+	err = os.Rename(tmp, final)
+	if err != nil {
+		return "", err
+	}
+
+	return final, nil
+}
+
+// copy the databases into dir while writes to all of them are paused, readers are not blocked.
+// writers wait until every database is copied, at most maxPause
+func copyPaused(ctx context.Context, dataDir, dir string, files []string) error {
+	ctx, cancel := context.WithTimeout(ctx, maxPause)
+	defer cancel()
+
+	for _, file := range files {
+		unlock, err := lockWriters(ctx, filepath.Join(dataDir, file))
+		if err != nil {
+			return pauseError(ctx, xerrors.Errorf("pause writes to %s: %w", file, err))
+		}
+		defer unlock()
+	}
+
+	for _, file := range files {
+		err := copyDatabase(ctx, filepath.Join(dataDir, file), filepath.Join(dir, file))
+		if err != nil {
+			return pauseError(ctx, xerrors.Errorf("snapshot %s: %w", file, err))
+		}
+	}
+
+	return nil
+}
+
+func pauseError(ctx context.Context, err error) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return xerrors.Errorf("writes can not be paused longer than %s: %w", maxPause, err)
+	}
+	return err
+}
+
+// check every database of a snapshot with PRAGMA integrity_check
+func Verify(snapshot string) error {
+	files, err := listDatabases(snapshot)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return xerrors.Errorf("no sqlite database in snapshot %s", snapshot)
+	}
+
+	for _, file := range files {
+		err = checkIntegrity(filepath.Join(snapshot, file))
+		if err != nil {
+			return xerrors.Errorf("snapshot %s: %w", file, err)
+		}
+	}
+
+	return nil
+}
+
+// replace the databases in dataDir with the ones of a verified snapshot, the validator must be stopped.
+// each replaced database is kept next to it with the .pre-restore suffix, together with its journals
+func Restore(snapshot, dataDir string) ([]string, error) {
+	dataDir, err := homedir.Expand(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err = homedir.Expand(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	err = Verify(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := listDatabases(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(dataDir, 0750)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		dst := filepath.Join(dataDir, file)
+		tmp := dst + ".restore"
+		err = copyFile(filepath.Join(snapshot, file), tmp)
+		if err != nil {
+			return nil, err
+		}
+
+		// the journals hold committed changes of the replaced database, they are moved with it
+		// so it stays complete, and are not applied to the restored one
+		for _, suffix := range []string{"", "-wal", "-shm", "-journal"} {
+			// left by an earlier restore, it must not pair with the database moved now
+			err = os.Remove(dst + preRestoreSuffix + suffix)
+			if err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+
+			err = os.Rename(dst+suffix, dst+preRestoreSuffix+suffix)
+			if err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+		}
+
+		err = os.Rename(tmp, dst)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// remove the oldest snapshots in dir beyond keep, and unfinished ones
+func Prune(dir string, keep int) error {
+	dir, err := homedir.Expand(dir)
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := List(dir)
+	if err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), snapshotPrefix) && strings.HasSuffix(entry.Name(), ".tmp") {
+			logger.Infof("remove unfinished snapshot %s", entry.Name())
+			err = os.RemoveAll(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if keep <= 0 || len(snapshots) <= keep {
+		return nil
+	}
+	for _, snapshot := range snapshots[:len(snapshots)-keep] {
+		logger.Infof("remove snapshot %s", filepath.Base(snapshot))
+		err = os.RemoveAll(snapshot)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// finished snapshots in dir, oldest first
+func List(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var snapshots []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || !strings.HasPrefix(name, snapshotPrefix) || strings.HasSuffix(name, ".tmp") {
+			continue
+		}
+		snapshots = append(snapshots, filepath.Join(dir, name))
+	}
+	sort.Strings(snapshots)
+
+	return snapshots, nil
+}
+
+// take a snapshot every interval and keep the latest ones, until ctx is done.
+// a failed snapshot is logged and retried at the next interval
+func Run(ctx context.Context, cfg Config) error {
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		snapshot, err := Snapshot(ctx, cfg.DataDir, cfg.Dir)
+		if err != nil {
+			logger.Errorf("snapshot failed: %s", err)
+			continue
+		}
+		logger.Infof("snapshot %s taken and verified", snapshot)
+
+		err = Prune(cfg.Dir, cfg.Keep)
+		if err != nil {
+			logger.Errorf("prune snapshots: %s", err)
+		}
+	}
+}
+
+// names of the sqlite databases in dir, told by their header
+func listDatabases(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || strings.HasSuffix(name, preRestoreSuffix) || strings.HasSuffix(name, ".restore") {
+			continue
+		}
+
+		ok, err := isSQLite(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			files = append(files, name)
+		}
+	}
+
+	return files, nil
+}
+
+func isSQLite(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	header := make([]byte, len(sqliteHeader))
+	_, err = io.ReadFull(f, header)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(header, sqliteHeader), nil
+}
+
+func openSQLite(dsn string) (*gorm.DB, error) {
+	return gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger: gormlogger.Discard,
+	})
+}
+
+func closeDB(db *gorm.DB) {
+	sqlDB, err := db.DB()
+	if err == nil {
+		sqlDB.Close()
+	}
+}
+
+// hold the write lock of a database until unlock is called
+func lockWriters(ctx context.Context, path string) (func(), error) {
+	db, err := openSQLite(fmt.Sprintf("file:%s?_busy_timeout=%d&_txlock=immediate", path, maxPause.Milliseconds()))
+	if err != nil {
+		return nil, err
+	}
+
+	tx := db.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		closeDB(db)
+		return nil, tx.Error
+	}
+
+	return func() {
+		tx.Rollback()
+		closeDB(db)
+	}, nil
+}
+
+func copyDatabase(ctx context.Context, src, dst string) error {
+	// the copy waits for the writers instead of failing
+	db, err := openSQLite(fmt.Sprintf("file:%s?_busy_timeout=%d", src, maxPause.Milliseconds()))
+	if err != nil {
+		return err
+	}
+	defer closeDB(db)
+
+	return db.WithContext(ctx).Exec("VACUUM INTO ?", dst).Error
+}
+
+func checkIntegrity(path string) error {
+	db, err := openSQLite("file:" + path + "?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer closeDB(db)
+
+	var res []string
+	err = db.Raw("PRAGMA integrity_check").Scan(&res).Error
+	if err != nil {
+		return err
+	}
+	if len(res) != 1 || res[0] != "ok" {
+		return xerrors.Errorf("integrity check failed: %s", strings.Join(res, "; "))
+	}
+
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, in)
+	if err == nil {
+		// the copy must be on disk before it replaces the database
+		err = out.Sync()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
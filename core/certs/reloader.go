@@ -0,0 +1,201 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gridprotocol/validator/logs"
+
+	"golang.org/x/xerrors"
+)
+
+var logger = logs.Logger("grid certs")
+
+// default interval of checking the files for changes
+const DefaultReloadInterval = 10 * time.Second
+
+// serve certificate and client ca from files, reloaded when the files change
+type Reloader struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+
+	lk       sync.RWMutex
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+	// modification times of the files at the last load attempt
+	modTimes []time.Time
+}
+
+// load the key pair, and the client ca bundle if given to verify client certificates
+func NewReloader(certFile, keyFile, clientCAFile string) (*Reloader, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, xerrors.New("tls certificate and key are both required")
+	}
+
+	r := &Reloader{
+		certFile:     certFile,
+		keyFile:      keyFile,
+		clientCAFile: clientCAFile,
+	}
+
+	err := r.reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// tls config using the latest loaded files, client certificates are verified when given
+func (r *Reloader) TLSConfig() *tls.Config {
+	cfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.getCertificate,
+	}
+
+	if r.clientCAFile != "" {
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+		cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			c := cfg.Clone()
+			c.GetConfigForClient = nil
+
+			r.lk.RLock()
+			c.ClientCAs = r.clientCA
+			r.lk.RUnlock()
+
+			return c, nil
+		}
+	}
+
+	return cfg
+}
+
+// whether client certificates are verified
+func (r *Reloader) MutualTLS() bool {
+	return r.clientCAFile != ""
+}
+
+// check the files every interval and reload them on change, a broken file keeps the last good one
+func (r *Reloader) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		changed, err := r.changed()
+		if err != nil {
+			logger.Warnf("check tls files: %s", err)
+			continue
+		}
+		if !changed {
+			continue
+		}
+
+		err = r.reload()
+		if err != nil {
+			logger.Errorf("reload tls files, keep the last loaded: %s", err)
+			continue
+		}
+		logger.Info("tls files reloaded")
+	}
+}
+
+func (r *Reloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.lk.RLock()
+	defer r.lk.RUnlock()
+
+	return r.cert, nil
+}
+
+func (r *Reloader) files() []string {
+	files := []string{r.certFile, r.keyFile}
+	if r.clientCAFile != "" {
+		files = append(files, r.clientCAFile)
+	}
+	return files
+}
+
+func (r *Reloader) stat() ([]time.Time, error) {
+	var modTimes []time.Time
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		modTimes = append(modTimes, info.ModTime())
+	}
+	return modTimes, nil
+}
+
+func (r *Reloader) changed() (bool, error) {
+	modTimes, err := r.stat()
+	if err != nil {
+		return false, err
+	}
+
+	r.lk.RLock()
+	defer r.lk.RUnlock()
+
+	for i := range modTimes {
+		if !modTimes[i].Equal(r.modTimes[i]) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *Reloader) reload() error {
+	modTimes, err := r.stat()
+	if err != nil {
+		return err
+	}
+
+	// a broken file is tried again only after it changes
+	r.lk.Lock()
+	r.modTimes = modTimes
+	r.lk.Unlock()
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return xerrors.Errorf("load key pair: %w", err)
+	}
+
+	var clientCA *x509.CertPool
+	if r.clientCAFile != "" {
+		clientCA, err = LoadCertPool(r.clientCAFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	r.cert = &cert
+	r.clientCA = clientCA
+	return nil
+}
+
+// load a pem bundle of ca certificates
+func LoadCertPool(file string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, xerrors.Errorf("no certificate found in %s", file)
+	}
+	return pool, nil
+}
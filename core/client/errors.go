@@ -0,0 +1,58 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gridprotocol/validator/logs"
+
+	"golang.org/x/xerrors"
+)
+
+// error returned by the validator api, compare with errors.Is against the Err* values
+type APIError struct {
+	StatusCode  int
+	Code        string
+	Description string
+	RequestID   string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s: %s (status %d, request %s)", e.Code, e.Description, e.StatusCode, e.RequestID)
+}
+
+// errors match by code
+func (e *APIError) Is(target error) bool {
+	t, ok := target.(*APIError)
+	return ok && t.Code == e.Code
+}
+
+func codeOf(errCode logs.APIErrorCode) *APIError {
+	return &APIError{Code: logs.ErrorCodes[errCode].Code}
+}
+
+var (
+	ErrBadRequest         = codeOf(logs.ErrBadRequest)
+	ErrUnavailable        = codeOf(logs.ErrUnavailable)
+	ErrRateLimited        = codeOf(logs.ErrRateLimited)
+	ErrProofOutsideWindow = codeOf(logs.ErrProofOutsideWindow)
+	ErrProofBadPOW        = codeOf(logs.ErrProofBadPOW)
+	ErrProofNotChallenged = codeOf(logs.ErrProofNotChallenged)
+	ErrProofDuplicate     = codeOf(logs.ErrProofDuplicate)
+)
+
+// decode the error body of a failed response, op describes the call
+func decodeError(statusCode int, body []byte, op string) error {
+	var errRes logs.APIErrorResponse
+	err := json.Unmarshal(body, &errRes)
+	if err != nil || errRes.Code == "" {
+		return xerrors.Errorf("Failed to %s, status [%d]", op, statusCode)
+	}
+
+	return &APIError{
+		StatusCode:  statusCode,
+		Code:        errRes.Code,
+		Description: errRes.Description,
+		RequestID:   errRes.RequestID,
+	}
+}
@@ -1,60 +1,162 @@
 package client
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"io"
+	"math/big"
+	"math/rand"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"time"
 
 	"github.com/gridprotocol/validator/core/types"
-
-	"golang.org/x/xerrors"
 )
 
+// client version sent in headers
+var Version = "1.0.0"
+
+const VersionHeader = "X-Client-Version"
+
 type GRIDClient struct {
-	baseUrl string
+	// base urls of validators, failover in order
+	baseUrls []string
+	// index of the base url that last succeeded
+	current atomic.Int64
+
+	httpClient *http.Client
+	userAgent  string
+
+	// tls settings applied to the transport
+	rootCAs     *x509.CertPool
+	clientCerts []tls.Certificate
+
+	// timeout of each attempt, 0 means none
+	timeout time.Duration
+	// retries of idempotent calls
+	retries    int
+	minBackoff time.Duration
+	maxBackoff time.Duration
 }
 
-func NewGRIDClient(url string) *GRIDClient {
-	return &GRIDClient{
-		baseUrl: url,
+type Option func(*GRIDClient)
+
+// use a custom http client, e.g. with its own transport
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *GRIDClient) {
+		c.httpClient = httpClient
 	}
 }
 
-type rndResult struct {
-	Rnd string
+// timeout of each request attempt
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *GRIDClient) {
+		c.timeout = timeout
+	}
 }
 
-func (c *GRIDClient) GetRND(ctx context.Context) ([32]byte, error) {
-	var url = c.baseUrl + "/rnd"
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return [32]byte{}, err
+// retry idempotent calls with jittered exponential backoff
+func WithRetry(retries int, minBackoff, maxBackoff time.Duration) Option {
+	return func(c *GRIDClient) {
+		c.retries = retries
+		c.minBackoff = minBackoff
+		c.maxBackoff = maxBackoff
 	}
+}
 
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return [32]byte{}, err
+// more validator base urls, tried when the previous one fails
+func WithFailover(urls ...string) Option {
+	return func(c *GRIDClient) {
+		c.baseUrls = append(c.baseUrls, urls...)
 	}
+}
 
-	if res.StatusCode != http.StatusOK {
-		return [32]byte{}, xerrors.Errorf("Failed to get rnd, status [%d]", res.StatusCode)
+// trust the validators signed by these cas, e.g. from certs.LoadCertPool
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(c *GRIDClient) {
+		c.rootCAs = pool
 	}
-	body, err := io.ReadAll(res.Body)
-	defer res.Body.Close()
-	if err != nil {
-		return [32]byte{}, err
+}
+
+// present a client certificate, required for privileged routes on validators with mutual tls
+func WithClientCertificate(cert tls.Certificate) Option {
+	return func(c *GRIDClient) {
+		c.clientCerts = append(c.clientCerts, cert)
+	}
+}
+
+func WithUserAgent(userAgent string) Option {
+	return func(c *GRIDClient) {
+		c.userAgent = userAgent
+	}
+}
+
+func NewGRIDClient(url string, opts ...Option) *GRIDClient {
+	c := &GRIDClient{
+		baseUrls:   []string{url},
+		httpClient: http.DefaultClient,
+		userAgent:  "grid-validator-client/" + Version,
+		timeout:    30 * time.Second,
+		retries:    3,
+		minBackoff: 200 * time.Millisecond,
+		maxBackoff: 5 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(c)
 	}
 
-	var rndRes rndResult
-	err = json.Unmarshal(body, &rndRes)
+	if c.rootCAs != nil || len(c.clientCerts) > 0 {
+		c.applyTLS()
+	}
+
+	return c
+}
+
+// copy the http client with tls settings on its transport, a custom non http.Transport is kept as is
+func (c *GRIDClient) applyTLS() {
+	var transport *http.Transport
+	switch t := c.httpClient.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return
+	}
+
+	tlsConfig := transport.TLSClientConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if c.rootCAs != nil {
+		tlsConfig.RootCAs = c.rootCAs
+	}
+	tlsConfig.Certificates = append(tlsConfig.Certificates, c.clientCerts...)
+	transport.TLSClientConfig = tlsConfig
+
+	httpClient := *c.httpClient
+	httpClient.Transport = transport
+	c.httpClient = &httpClient
+}
+
+func (c *GRIDClient) GetRND(ctx context.Context) ([32]byte, error) {
+	var rndRes types.RNDResponse
+	err := c.getJSON(ctx, "/rnd", "get rnd", &rndRes)
 	if err != nil {
 		return [32]byte{}, err
 	}
 
-	rndBytes, err := hex.DecodeString(rndRes.Rnd)
+	rndBytes, err := hex.DecodeString(rndRes.RND)
 	if err != nil {
 		return [32]byte{}, err
 	}
@@ -66,8 +168,6 @@ func (c *GRIDClient) GetRND(ctx context.Context) ([32]byte, error) {
 
 // send proof with http request
 func (c *GRIDClient) SubmitProof(ctx context.Context, proof types.Proof) error {
-	var url = c.baseUrl + "/proof"
-
 	payload := make(map[string]interface{})
 
 	payload["provider"] = proof.Provider
@@ -78,21 +178,345 @@ func (c *GRIDClient) SubmitProof(ctx context.Context, proof types.Proof) error {
 		return err
 	}
 
-	// new post request
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(b))
+	// not retried, a repeated proof is rejected as duplicate
+	_, err = c.do(ctx, "POST", "/proof", b, false, "submit proof")
+	return err
+}
+
+// send proofs in one request, get a verdict for each proof
+func (c *GRIDClient) SubmitProofs(ctx context.Context, proofs []types.Proof) ([]types.ProofVerdict, error) {
+	b, err := json.Marshal(proofs)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := c.do(ctx, "POST", "/proofs", b, false, "submit proofs")
+	if err != nil {
+		return nil, err
+	}
+
+	var verdicts []types.ProofVerdict
+	err = json.Unmarshal(body, &verdicts)
+	if err != nil {
+		return nil, err
+	}
+
+	return verdicts, nil
+}
+
+// get validator signature to withdraw amount of profit
+func (c *GRIDClient) GetWithdrawSignature(ctx context.Context, address string, amount *big.Int) ([]byte, error) {
+	query := url.Values{}
+	query.Set("address", address)
+	query.Set("amount", amount.String())
+
+	var signature string
+	err := c.getJSON(ctx, "/withdraw/signature?"+query.Encode(), "get withdraw signature", &signature)
+	if err != nil {
+		return nil, err
+	}
+
+	return hex.DecodeString(signature)
+}
+
+// get order count of a provider
+func (c *GRIDClient) GetOrderCount(ctx context.Context, address string) (int64, error) {
+	var cnt int64
+	err := c.getJSON(ctx, "/provider/"+url.PathEscape(address)+"/count", "get order count", &cnt)
+	if err != nil {
+		return 0, err
+	}
+
+	return cnt, nil
+}
+
+// get profit record of a provider
+func (c *GRIDClient) GetProfit(ctx context.Context, address string) (types.ProfitInfo, error) {
+	var profit types.ProfitInfo
+	err := c.getJSON(ctx, "/provider/"+url.PathEscape(address)+"/profit", "get profit", &profit)
+	return profit, err
+}
+
+// get active orders of a provider
+func (c *GRIDClient) GetOrders(ctx context.Context, address string) ([]types.OrderInfo, error) {
+	var orders []types.OrderInfo
+	err := c.getJSON(ctx, "/provider/"+url.PathEscape(address)+"/orders", "get orders", &orders)
+	return orders, err
+}
+
+// get challenge status of the nodes of a provider
+func (c *GRIDClient) GetNodes(ctx context.Context, address string) ([]types.NodeStatus, error) {
+	var nodes []types.NodeStatus
+	err := c.getJSON(ctx, "/provider/"+url.PathEscape(address)+"/nodes", "get nodes", &nodes)
+	return nodes, err
+}
+
+// get validator wide statistics over the last cycles, 0 uses the server default
+func (c *GRIDClient) GetStats(ctx context.Context, cycles int64) (types.Stats, error) {
+	path := "/stats"
+	if cycles > 0 {
+		path += "?cycles=" + strconv.FormatInt(cycles, 10)
+	}
+
+	var stats types.Stats
+	err := c.getJSON(ctx, path, "get stats", &stats)
+	return stats, err
+}
+
+// get the OpenAPI document of the validator api
+func (c *GRIDClient) GetOpenAPI(ctx context.Context) ([]byte, error) {
+	var spec json.RawMessage
+	err := c.getJSON(ctx, "/openapi.json", "get openapi", &spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return spec, nil
+}
+
+// send get request and decode json body into out
+func (c *GRIDClient) getJSON(ctx context.Context, path string, op string, out interface{}) error {
+	body, err := c.do(ctx, "GET", path, nil, true, op)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(body, out)
+}
+
+// receive cycle events until ctx is canceled or the stream ends, then the channel is closed.
+// the stream is not resumed, subscribe again to reconnect
+func (c *GRIDClient) Subscribe(ctx context.Context) (<-chan types.Event, error) {
+	req, err := c.newRequest(ctx, "GET", c.baseUrls[c.current.Load()]+"/events", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	// no per call timeout on a stream
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		return nil, decodeError(res.StatusCode, body, "subscribe events")
+	}
+
+	events := make(chan types.Event, 64)
+	go func() {
+		defer close(events)
+		defer res.Body.Close()
+
+		// an event ends with an empty line, data may span lines
+		var data []byte
+		scanner := bufio.NewScanner(res.Body)
+		// the verdicts of a cycle come in one event
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			if len(line) == 0 {
+				if len(data) == 0 {
+					continue
+				}
+
+				var event types.Event
+				err := json.Unmarshal(data, &event)
+				data = data[:0]
+				if err != nil {
+					continue
+				}
+
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+				continue
+			}
+
+			value, ok := bytes.CutPrefix(line, []byte("data:"))
+			if !ok {
+				// event name, id and comments are ignored, type is in data
+				continue
+			}
+			if len(data) > 0 {
+				data = append(data, '\n')
+			}
+			data = append(data, bytes.TrimPrefix(value, []byte(" "))...)
+		}
+	}()
+
+	return events, nil
+}
+
+// stream an export into w as it is received, the validator may require a client certificate.
+// a partly written w is left on error
+func (c *GRIDClient) Export(ctx context.Context, q types.ExportQuery, w io.Writer) error {
+	query := url.Values{}
+	query.Set("dataset", q.Dataset)
+	if q.Format != "" {
+		query.Set("format", q.Format)
+	}
+	if q.FromCycle > 0 {
+		query.Set("from_cycle", strconv.FormatInt(q.FromCycle, 10))
+	}
+	if q.ToCycle > 0 {
+		query.Set("to_cycle", strconv.FormatInt(q.ToCycle, 10))
+	}
+	if !q.From.IsZero() {
+		query.Set("from", q.From.UTC().Format("2006-01-02"))
+	}
+	if !q.To.IsZero() {
+		query.Set("to", q.To.UTC().Format("2006-01-02"))
+	}
+	if q.Provider != "" {
+		query.Set("provider", q.Provider)
+	}
+
+	req, err := c.newRequest(ctx, "GET", c.baseUrls[c.current.Load()]+"/export?"+query.Encode(), nil)
 	if err != nil {
 		return err
 	}
 
-	// send request
-	res, err := http.DefaultClient.Do(req)
+	// no per call timeout on a stream
+	res, err := c.httpClient.Do(req)
 	if err != nil {
 		return err
 	}
+	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
-		return xerrors.Errorf("Failed to submit proof, status [%d]", res.StatusCode)
+		body, _ := io.ReadAll(res.Body)
+		return decodeError(res.StatusCode, body, "export")
+	}
+
+	_, err = io.Copy(w, res.Body)
+	if err != nil {
+		return err
+	}
+
+	// trailers are only known after the body
+	msg := res.Trailer.Get(types.ExportErrorTrailer)
+	if msg != "" {
+		return errors.New("export failed: " + msg)
 	}
 
 	return nil
 }
+
+// new request with client headers
+func (c *GRIDClient) newRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set(VersionHeader, Version)
+	return req, nil
+}
+
+// send request and return body of a 200 response.
+// idempotent requests are retried on network errors, 429 and 5xx; a network error moves to the next base url.
+// other requests only move to the next base url when the connection was not established
+func (c *GRIDClient) do(ctx context.Context, method, path string, payload []byte, idempotent bool, op string) ([]byte, error) {
+	attempts := 1
+	if idempotent {
+		attempts += c.retries
+	}
+
+	index := c.current.Load()
+	backoff := c.minBackoff
+	for attempt := 0; ; attempt++ {
+		body, retryAfter, err := c.send(ctx, c.baseUrls[index]+path, method, payload, op)
+		if err == nil {
+			c.current.Store(index)
+			return body, nil
+		}
+
+		var opErr *net.OpError
+		dialFailed := errors.As(err, &opErr) && opErr.Op == "dial"
+		if retryAfter == 0 {
+			index = (index + 1) % int64(len(c.baseUrls))
+		}
+
+		// not sent, try the next base url at once
+		if !idempotent && dialFailed && attempt+1 < len(c.baseUrls) {
+			continue
+		}
+
+		if attempt+1 >= attempts || retryAfter < 0 {
+			return nil, err
+		}
+
+		// full backoff with jitter, unless the server asks for a longer wait
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+
+		backoff *= 2
+		if backoff > c.maxBackoff {
+			backoff = c.maxBackoff
+		}
+	}
+}
+
+// send one attempt, retryAfter is 0 for network errors and negative if the error is not retryable
+func (c *GRIDClient) send(ctx context.Context, url, method string, payload []byte, op string) ([]byte, time.Duration, error) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	var reqBody io.Reader
+	if payload != nil {
+		reqBody = bytes.NewReader(payload)
+	}
+
+	req, err := c.newRequest(ctx, method, url, reqBody)
+	if err != nil {
+		return nil, -1, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if res.StatusCode == http.StatusOK {
+		return body, 0, nil
+	}
+
+	err = decodeError(res.StatusCode, body, op)
+	if res.StatusCode != http.StatusTooManyRequests && res.StatusCode < 500 {
+		return nil, -1, err
+	}
+
+	retryAfter := time.Nanosecond
+	seconds, perr := strconv.Atoi(res.Header.Get("Retry-After"))
+	if perr == nil && seconds > 0 {
+		retryAfter = time.Duration(seconds) * time.Second
+	}
+
+	return nil, retryAfter, err
+}
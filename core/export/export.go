@@ -0,0 +1,158 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/gridprotocol/validator/core/store"
+	"github.com/gridprotocol/validator/core/types"
+	"github.com/gridprotocol/validator/logs"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// exported records
+const (
+	DatasetCycles  = "cycles"
+	DatasetResults = "results"
+	DatasetLedger  = "ledger"
+)
+
+// layout of the from and to dates
+const DateFormat = "2006-01-02"
+
+// check the query before anything is written
+func Check(q types.ExportQuery) error {
+	switch q.Dataset {
+	case DatasetCycles:
+		if q.Provider != "" {
+			return logs.BadRequest{Message: "cycles are validator wide, they can not be filtered by provider"}
+		}
+	case DatasetResults, DatasetLedger:
+		if q.Provider != "" && !common.IsHexAddress(q.Provider) {
+			return logs.BadRequest{Message: "invalid provider address " + q.Provider}
+		}
+	default:
+		return logs.BadRequest{Message: "dataset must be cycles, results or ledger"}
+	}
+
+	_, ok := formats[q.Format]
+	if !ok {
+		return logs.BadRequest{Message: "format must be csv, jsonl or parquet"}
+	}
+
+	if q.FromCycle < 0 || q.ToCycle < 0 {
+		return logs.BadRequest{Message: "cycles must not be negative"}
+	}
+	if q.ToCycle > 0 && q.ToCycle < q.FromCycle {
+		return logs.BadRequest{Message: "to_cycle is before from_cycle"}
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && q.To.Before(q.From) {
+		return logs.BadRequest{Message: "to is before from"}
+	}
+
+	return nil
+}
+
+// records of the query, dates are matched on the start time of the cycles
+func Filter(q types.ExportQuery) store.ExportFilter {
+	filter := store.ExportFilter{
+		FromCycle: q.FromCycle,
+		ToCycle:   q.ToCycle,
+		From:      q.From,
+	}
+	if !q.To.IsZero() {
+		// To is a whole day
+		filter.To = q.To.AddDate(0, 0, 1)
+	}
+	if q.Provider != "" {
+		// the records are stored with the checksum case
+		filter.Provider = common.HexToAddress(q.Provider).Hex()
+	}
+
+	return filter
+}
+
+// name of the exported file, e.g.(grid-ledger-100-200.csv, grid-cycles-2024-12-01-2024-12-31.csv)
+func FileName(q types.ExportQuery, filter store.ExportFilter) string {
+	name := "grid-" + q.Dataset
+	if filter.Provider != "" {
+		name += "-" + filter.Provider
+	}
+	if filter.FromCycle > 0 || filter.ToCycle > 0 {
+		name += "-" + strconv.FormatInt(filter.FromCycle, 10) + "-"
+		if filter.ToCycle > 0 {
+			name += strconv.FormatInt(filter.ToCycle, 10)
+		}
+	}
+	if !q.From.IsZero() || !q.To.IsZero() {
+		name += "-" + formatDate(q.From) + "-" + formatDate(q.To)
+	}
+
+	return name + "." + formats[q.Format].extension
+}
+
+// empty for an open bound
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(DateFormat)
+}
+
+func ContentType(format string) string {
+	return formats[format].contentType
+}
+
+// write the records of a checked query to w as they are read from src, they are never all in memory.
+// a failed export leaves a truncated output
+func Write(ctx context.Context, src store.Exporter, w io.Writer, q types.ExportQuery, filter store.ExportFilter) error {
+	out := bufio.NewWriterSize(w, 64*1024)
+
+	var err error
+	switch q.Dataset {
+	case DatasetCycles:
+		err = writeRows(out, q.Format, cycleColumns, func(write func(cycleRow) error) error {
+			return src.EachCycle(ctx, filter, func(cycle store.Cycle) error {
+				return write(newCycleRow(cycle))
+			})
+		})
+	case DatasetResults:
+		err = writeRows(out, q.Format, resultColumns, func(write func(resultRow) error) error {
+			return src.EachNodeResult(ctx, filter, func(result store.NodeResult) error {
+				return write(newResultRow(result))
+			})
+		})
+	case DatasetLedger:
+		err = writeRows(out, q.Format, ledgerColumns, func(write func(ledgerRow) error) error {
+			return src.EachLedger(ctx, filter, func(entry store.Ledger) error {
+				return write(newLedgerRow(entry))
+			})
+		})
+	default:
+		err = Check(q)
+	}
+	if err != nil {
+		return err
+	}
+
+	return out.Flush()
+}
+
+// write the rows produced by each in a format
+func writeRows[T row](w io.Writer, format string, columns []string, each func(write func(T) error) error) error {
+	rw, err := newRowWriter[T](w, format, columns)
+	if err != nil {
+		return err
+	}
+
+	err = each(rw.write)
+	if err != nil {
+		return err
+	}
+
+	return rw.close()
+}
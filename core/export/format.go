@@ -0,0 +1,130 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+
+	"github.com/gridprotocol/validator/logs"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// export formats
+const (
+	FormatCSV = "csv"
+	// one json object per line
+	FormatJSONL   = "jsonl"
+	FormatParquet = "parquet"
+)
+
+type format struct {
+	extension   string
+	contentType string
+}
+
+var formats = map[string]format{
+	FormatCSV:     {extension: "csv", contentType: "text/csv; charset=utf-8"},
+	FormatJSONL:   {extension: "jsonl", contentType: "application/x-ndjson"},
+	FormatParquet: {extension: "parquet", contentType: "application/vnd.apache.parquet"},
+}
+
+// rows written to a parquet row group before it is flushed, bounds the memory of an export
+const parquetRowGroup = 64 * 1024
+
+// rows buffered before they are handed to the parquet writer
+const parquetBatch = 1024
+
+type rowWriter[T row] interface {
+	write(r T) error
+	// flush the rows, the output is complete after it
+	close() error
+}
+
+func newRowWriter[T row](w io.Writer, format string, columns []string) (rowWriter[T], error) {
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		// the header is written even if there are no rows
+		err := cw.Write(columns)
+		if err != nil {
+			return nil, err
+		}
+		return &csvWriter[T]{w: cw}, nil
+	case FormatJSONL:
+		return &jsonlWriter[T]{enc: json.NewEncoder(w)}, nil
+	case FormatParquet:
+		return &parquetWriter[T]{w: parquet.NewGenericWriter[T](w)}, nil
+	}
+
+	return nil, logs.BadRequest{Message: "format must be csv, jsonl or parquet"}
+}
+
+type csvWriter[T row] struct {
+	w *csv.Writer
+}
+
+func (c *csvWriter[T]) write(r T) error {
+	return c.w.Write(r.record())
+}
+
+func (c *csvWriter[T]) close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type jsonlWriter[T row] struct {
+	enc *json.Encoder
+}
+
+func (j *jsonlWriter[T]) write(r T) error {
+	return j.enc.Encode(r)
+}
+
+func (j *jsonlWriter[T]) close() error {
+	return nil
+}
+
+// the parquet footer is written by close, a file without it is unreadable
+type parquetWriter[T row] struct {
+	w     *parquet.GenericWriter[T]
+	batch []T
+	// rows in the current row group
+	rows int
+}
+
+func (p *parquetWriter[T]) write(r T) error {
+	p.batch = append(p.batch, r)
+	if len(p.batch) < parquetBatch {
+		return nil
+	}
+
+	return p.flushBatch()
+}
+
+func (p *parquetWriter[T]) flushBatch() error {
+	n, err := p.w.Write(p.batch)
+	if err != nil {
+		return err
+	}
+	p.batch = p.batch[:0]
+
+	p.rows += n
+	if p.rows >= parquetRowGroup {
+		p.rows = 0
+		return p.w.Flush()
+	}
+
+	return nil
+}
+
+func (p *parquetWriter[T]) close() error {
+	if len(p.batch) > 0 {
+		err := p.flushBatch()
+		if err != nil {
+			return err
+		}
+	}
+
+	return p.w.Close()
+}
@@ -0,0 +1,133 @@
+package export
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gridprotocol/validator/core/store"
+)
+
+// exported record, fields are the columns of every format
+type row interface {
+	// values of the csv columns
+	record() []string
+}
+
+var cycleColumns = []string{"cycle", "start_time", "status", "policy", "challenged", "passed", "failed", "settled_at"}
+
+type cycleRow struct {
+	Cycle      int64     `json:"cycle" parquet:"cycle"`
+	StartTime  time.Time `json:"start_time" parquet:"start_time,timestamp(millisecond)"`
+	Status     string    `json:"status" parquet:"status,dict"`
+	Policy     string    `json:"policy" parquet:"policy,dict"`
+	Challenged int64     `json:"challenged" parquet:"challenged"`
+	Passed     int64     `json:"passed" parquet:"passed"`
+	Failed     int64     `json:"failed" parquet:"failed"`
+	SettledAt  time.Time `json:"settled_at" parquet:"settled_at,timestamp(millisecond)"`
+}
+
+func newCycleRow(cycle store.Cycle) cycleRow {
+	return cycleRow{
+		Cycle:      cycle.ID,
+		StartTime:  cycle.StartTime.UTC(),
+		Status:     cycle.Status,
+		Policy:     cycle.Policy,
+		Challenged: int64(cycle.Challenged),
+		Passed:     int64(cycle.Passed),
+		Failed:     int64(cycle.Failed),
+		SettledAt:  cycle.SettledAt.UTC(),
+	}
+}
+
+func (r cycleRow) record() []string {
+	return []string{
+		strconv.FormatInt(r.Cycle, 10),
+		formatTime(r.StartTime),
+		r.Status,
+		r.Policy,
+		strconv.FormatInt(r.Challenged, 10),
+		strconv.FormatInt(r.Passed, 10),
+		strconv.FormatInt(r.Failed, 10),
+		formatTime(r.SettledAt),
+	}
+}
+
+var resultColumns = []string{"cycle", "provider", "node_id", "passed"}
+
+type resultRow struct {
+	Cycle    int64  `json:"cycle" parquet:"cycle"`
+	Provider string `json:"provider" parquet:"provider,dict"`
+	NodeID   uint64 `json:"node_id" parquet:"node_id"`
+	Passed   bool   `json:"passed" parquet:"passed"`
+}
+
+func newResultRow(result store.NodeResult) resultRow {
+	return resultRow{
+		Cycle:    result.Cycle,
+		Provider: result.Provider,
+		NodeID:   result.NodeID,
+		Passed:   result.Passed,
+	}
+}
+
+func (r resultRow) record() []string {
+	return []string{
+		strconv.FormatInt(r.Cycle, 10),
+		r.Provider,
+		strconv.FormatUint(r.NodeID, 10),
+		strconv.FormatBool(r.Passed),
+	}
+}
+
+var ledgerColumns = []string{"id", "cycle", "provider", "node_id", "reason", "reward", "penalty", "balance", "profit", "created_at"}
+
+// amounts are decimal strings, they do not fit in 64 bits
+type ledgerRow struct {
+	ID        uint64    `json:"id" parquet:"id"`
+	Cycle     int64     `json:"cycle" parquet:"cycle"`
+	Provider  string    `json:"provider" parquet:"provider,dict"`
+	NodeID    uint64    `json:"node_id" parquet:"node_id"`
+	Reason    string    `json:"reason" parquet:"reason,dict"`
+	Reward    string    `json:"reward" parquet:"reward"`
+	Penalty   string    `json:"penalty" parquet:"penalty"`
+	Balance   string    `json:"balance" parquet:"balance"`
+	Profit    string    `json:"profit" parquet:"profit"`
+	CreatedAt time.Time `json:"created_at" parquet:"created_at,timestamp(millisecond)"`
+}
+
+func newLedgerRow(entry store.Ledger) ledgerRow {
+	return ledgerRow{
+		ID:        entry.ID,
+		Cycle:     entry.Cycle,
+		Provider:  entry.Provider,
+		NodeID:    entry.NodeID,
+		Reason:    entry.Reason,
+		Reward:    entry.Reward,
+		Penalty:   entry.Penalty,
+		Balance:   entry.Balance,
+		Profit:    entry.Profit,
+		CreatedAt: entry.CreatedAt.UTC(),
+	}
+}
+
+func (r ledgerRow) record() []string {
+	return []string{
+		strconv.FormatUint(r.ID, 10),
+		strconv.FormatInt(r.Cycle, 10),
+		r.Provider,
+		strconv.FormatUint(r.NodeID, 10),
+		r.Reason,
+		r.Reward,
+		r.Penalty,
+		r.Balance,
+		r.Profit,
+		formatTime(r.CreatedAt),
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
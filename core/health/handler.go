@@ -0,0 +1,31 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// liveness, fails only when the validator loop has exited
+func (h *Checker) LivenessHandler(c *gin.Context) {
+	report := h.Live()
+
+	code := http.StatusOK
+	if !report.LoopAlive {
+		code = http.StatusServiceUnavailable
+	}
+
+	c.JSON(code, report)
+}
+
+// readiness, fails when the node should not receive traffic
+func (h *Checker) ReadinessHandler(c *gin.Context) {
+	report := h.Check(c.Request.Context())
+
+	code := http.StatusOK
+	if !report.Ready {
+		code = http.StatusServiceUnavailable
+	}
+
+	c.JSON(code, report)
+}
@@ -0,0 +1,88 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gridprotocol/validator/core/store"
+	"github.com/gridprotocol/validator/core/syncer"
+	"github.com/gridprotocol/validator/core/validator"
+	"github.com/gridprotocol/validator/logs"
+)
+
+// state reported by the health endpoints, a check is "ok" or the error message.
+// liveness leaves out the remote checks
+type Report struct {
+	Ready       bool          `json:"ready"`
+	Database    string        `json:"database,omitempty"`
+	Chain       string        `json:"chain,omitempty"`
+	Sync        syncer.Status `json:"sync"`
+	Phase       string        `json:"phase"`
+	LoopAlive   bool          `json:"loop_alive"`
+	LastSettled *time.Time    `json:"last_settled,omitempty"`
+}
+
+const checkOK = "ok"
+
+type Checker struct {
+	validator  *validator.GRIDValidator
+	supervisor *syncer.Supervisor
+
+	// timeout of each remote check
+	timeout time.Duration
+}
+
+func NewChecker(validator *validator.GRIDValidator, supervisor *syncer.Supervisor) *Checker {
+	return &Checker{
+		validator:  validator,
+		supervisor: supervisor,
+		timeout:    3 * time.Second,
+	}
+}
+
+// local state only, a slow database or rpc does not fail liveness
+func (h *Checker) Live() Report {
+	return Report{
+		Sync:      h.supervisor.Status(),
+		Phase:     h.validator.Phase(),
+		LoopAlive: h.validator.Alive(),
+	}
+}
+
+// check all dependencies, the node is ready when every check passes
+func (h *Checker) Check(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	report := h.Live()
+	report.Database = checkOK
+	report.Chain = checkOK
+
+	db := h.validator.Store()
+	err := db.Ping(ctx)
+	if err == nil {
+		var cycle store.Cycle
+		cycle, err = db.GetLastCycle()
+		if err == nil {
+			report.LastSettled = &cycle.SettledAt
+		} else if errors.Is(err, logs.ErrNotExist) {
+			err = nil
+		}
+	}
+	if err != nil {
+		report.Database = err.Error()
+	}
+
+	err = h.supervisor.Ping(ctx)
+	if err != nil {
+		report.Chain = err.Error()
+	}
+
+	report.Ready = report.Database == checkOK &&
+		report.Chain == checkOK &&
+		!h.supervisor.Stale() &&
+		report.LoopAlive
+
+	return report
+}
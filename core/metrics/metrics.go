@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "grid_validator"
+
+// reason label of proof verification
+const (
+	ReasonAccepted      = "accepted"
+	ReasonBadRequest    = "bad_request"
+	ReasonOutsideWindow = "outside_window"
+	ReasonDifficulty    = "difficulty"
+	ReasonBadPOW        = "bad_pow"
+	ReasonSaturated     = "saturated"
+	ReasonRateLimited   = "rate_limited"
+	// verified but not recorded, outside window, not challenged or duplicate
+	ReasonRejected = "rejected"
+)
+
+var (
+	// proof verification outcomes
+	ProofsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "proofs_total",
+		Help:      "Submitted proofs by verification outcome.",
+	}, []string{"reason"})
+
+	// node results of the last settled cycle
+	CycleNodes = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "cycle_nodes",
+		Help:      "Nodes of the last settled cycle by status (challenged, passed, failed).",
+	}, []string{"status"})
+
+	// node results of all settled cycles
+	CycleNodesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cycle_nodes_total",
+		Help:      "Nodes of all settled cycles by status (challenged, passed, failed).",
+	}, []string{"status"})
+
+	CyclesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cycles_total",
+		Help:      "Handled cycles by status (settled, recovered, skipped).",
+	}, []string{"status"})
+
+	SettlementDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "settlement_duration_seconds",
+		Help:      "Time to settle profits and record results of a cycle.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+	})
+
+	SettlementErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "settlement_errors_total",
+		Help:      "Cycles whose settlement failed.",
+	})
+
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "code"})
+
+	SyncedBlock = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sync_synced_block",
+		Help:      "Block the local db is synced to.",
+	})
+
+	HeadBlock = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sync_head_block",
+		Help:      "Latest chain head seen.",
+	})
+
+	SyncLag = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sync_lag_blocks",
+		Help:      "Blocks the local db is behind chain head.",
+	})
+
+	SyncRestarts = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sync_restarts_total",
+		Help:      "Restarts of the chain subscription.",
+	})
+
+	// backpressure of proof intake
+	IntakeInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "intake_in_flight",
+		Help:      "Proofs under verification.",
+	})
+
+	// abuse protection
+	RateLimitedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "Requests rejected by rate limiting by scope (ip, provider).",
+	}, []string{"scope"})
+
+	BansTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bans_total",
+		Help:      "Temporary bans after repeated invalid proofs by scope (ip, provider).",
+	}, []string{"scope"})
+)
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gridprotocol/validator/logs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requests are logged by the "gin" logger, so its level is set like any other
+var accessLogger = logs.Logger("gin")
+
+// log each request, server errors at error level and the rest at info
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		log := accessLogger.Infow
+		if status >= 500 {
+			log = accessLogger.Errorw
+		}
+
+		log(c.Request.Method+" "+c.Request.URL.Path,
+			"status", status,
+			"latency", time.Since(start),
+			"ip", c.ClientIP(),
+			"request_id", GetRequestID(c),
+		)
+	}
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/gridprotocol/validator/logs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// only let requests with "Authorization: Bearer <token>" through, none when the token is empty
+func BearerToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		got, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", "Bearer")
+			AbortWithError(c, logs.AuthenticationFailed{Message: "invalid or missing bearer token"})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"github.com/gridprotocol/validator/logs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// abort the request, the error is written by ErrorHandler
+func AbortWithError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
+// write the last error of the request as logs.APIErrorResponse, internal errors are logged
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := c.Errors.Last().Err
+		apiErr := logs.ToAPIErrorCode(err)
+		// the text of an untyped error is not returned, only logged
+		if apiErr.Code == logs.ErrorCodes.ToAPIErr(logs.ErrInternal).Code {
+			Logger(c).Errorw("request failed", "error", err)
+		}
+		c.JSON(apiErr.HTTPStatusCode, logs.APIErrorResponse{
+			Code:        apiErr.Code,
+			Description: apiErr.Description,
+			RequestID:   GetRequestID(c),
+		})
+	}
+}
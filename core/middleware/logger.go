@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"github.com/gridprotocol/validator/logs"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+var apiLogger = logs.Logger("grid api")
+
+const loggerKey = "logger"
+
+// attach a logger with request id, route, client ip and trace id to the request,
+// the provider is added from the address path param or by WithProvider
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		fields := []interface{}{
+			"request_id", GetRequestID(c),
+			"route", c.FullPath(),
+			"client_ip", c.ClientIP(),
+		}
+
+		id := traceID(c)
+		if id != "" {
+			fields = append(fields, "trace_id", id)
+		}
+
+		address := c.Param("address")
+		if address != "" {
+			fields = append(fields, "provider", address)
+		}
+
+		c.Set(loggerKey, apiLogger.With(fields...))
+		c.Next()
+	}
+}
+
+// logger of the request, or the plain api logger outside RequestLogger
+func Logger(c *gin.Context) *zap.SugaredLogger {
+	v, ok := c.Get(loggerKey)
+	if ok {
+		log, ok := v.(*zap.SugaredLogger)
+		if ok {
+			return log
+		}
+	}
+	return apiLogger
+}
+
+// add the provider to the request logger once it is known from the body
+func WithProvider(c *gin.Context, provider string) *zap.SugaredLogger {
+	log := Logger(c).With("provider", provider)
+	c.Set(loggerKey, log)
+	return log
+}
@@ -0,0 +1,249 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gridprotocol/validator/core/metrics"
+	"github.com/gridprotocol/validator/logs"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+var logger = logs.Logger("grid middleware")
+
+const (
+	ScopeIP       = "ip"
+	ScopeProvider = "provider"
+
+	limiterKey = "rate_limiter"
+
+	// idle buckets are dropped after this
+	limiterIdle = 10 * time.Minute
+	// interval of dropping idle buckets and expired bans
+	limiterSweep = time.Minute
+)
+
+// limits of the public api, a zero rate, size or threshold disables that check
+type RateLimitConfig struct {
+	// requests per second of one client ip
+	IPRate  float64
+	IPBurst int
+	// proof submissions per second of one provider
+	ProviderRate  float64
+	ProviderBurst int
+	// max bytes of a request body
+	MaxBodySize int64
+	// invalid proofs within BanWindow before the client ip is banned.
+	// the provider of a proof is not authenticated, so it is never banned
+	BanThreshold int
+	BanWindow    time.Duration
+	BanDuration  time.Duration
+}
+
+func DefaultRateLimitConfig() RateLimitConfig {
+	return RateLimitConfig{
+		IPRate:        20,
+		IPBurst:       100,
+		ProviderRate:  10,
+		ProviderBurst: 50,
+		MaxBodySize:   1 << 20, // 1 MiB
+		BanThreshold:  20,
+		BanWindow:     time.Minute,
+		BanDuration:   10 * time.Minute,
+	}
+}
+
+type bucket struct {
+	limiter *rate.Limiter
+	seen    time.Time
+}
+
+type strikes struct {
+	count int
+	since time.Time
+}
+
+// token buckets per ip and provider, and temporary bans after repeated invalid proofs
+type RateLimiter struct {
+	cfg RateLimitConfig
+
+	lk      sync.Mutex
+	buckets map[string]*bucket
+	strikes map[string]*strikes
+	bans    map[string]time.Time
+	swept   time.Time
+}
+
+func NewRateLimiter(cfg RateLimitConfig) *RateLimiter {
+	return &RateLimiter{
+		cfg:     cfg,
+		buckets: make(map[string]*bucket),
+		strikes: make(map[string]*strikes),
+		bans:    make(map[string]time.Time),
+		swept:   time.Now(),
+	}
+}
+
+// limit body size and requests per client ip, handlers reach the limiter with CheckProvider and ReportInvalidProof
+func (l *RateLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(limiterKey, l)
+
+		if l.cfg.MaxBodySize > 0 && c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, l.cfg.MaxBodySize)
+		}
+
+		wait, err := l.take(ScopeIP, c.ClientIP(), l.cfg.IPRate, l.cfg.IPBurst, 1)
+		if err != nil {
+			setRetryAfter(c, wait)
+			AbortWithError(c, err)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// take a token of the provider for each of its n proofs, the error is logs.RateLimited and Retry-After is set on the response
+func CheckProvider(c *gin.Context, provider string, n int) error {
+	l, ok := limiterOf(c)
+	if !ok {
+		return nil
+	}
+
+	wait, err := l.take(ScopeProvider, normalizeProvider(provider), l.cfg.ProviderRate, l.cfg.ProviderBurst, n)
+	if err != nil {
+		setRetryAfter(c, wait)
+	}
+	return err
+}
+
+// count an invalid proof against the client ip, it is banned once over the threshold.
+// anyone can send a proof in the name of a provider, so the provider is not struck
+func ReportInvalidProof(c *gin.Context) {
+	l, ok := limiterOf(c)
+	if !ok {
+		return
+	}
+
+	l.strike(ScopeIP, c.ClientIP())
+}
+
+func limiterOf(c *gin.Context) (*RateLimiter, bool) {
+	v, ok := c.Get(limiterKey)
+	if !ok {
+		return nil, false
+	}
+	l, ok := v.(*RateLimiter)
+	return l, ok
+}
+
+// take n tokens from the bucket of key, returns how long to wait if banned or out of tokens
+func (l *RateLimiter) take(scope, key string, limit float64, burst, n int) (time.Duration, error) {
+	now := time.Now()
+	id := scope + ":" + key
+
+	l.lk.Lock()
+	defer l.lk.Unlock()
+
+	l.sweep(now)
+
+	until, ok := l.bans[id]
+	if ok && now.Before(until) {
+		metrics.RateLimitedTotal.WithLabelValues(scope).Inc()
+		return until.Sub(now), logs.RateLimited{Message: fmt.Sprintf("%s %s is banned until %s for repeated invalid proofs", scope, key, until.Format(time.RFC3339))}
+	}
+
+	if limit <= 0 {
+		return 0, nil
+	}
+
+	b, ok := l.buckets[id]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(rate.Limit(limit), max(burst, 1))}
+		l.buckets[id] = b
+	}
+	b.seen = now
+
+	r := b.limiter.ReserveN(now, n)
+	if !r.OK() {
+		metrics.RateLimitedTotal.WithLabelValues(scope).Inc()
+		// waiting does not help, the batch must be split
+		return 0, logs.RateLimited{Message: fmt.Sprintf("%d requests from %s %s exceed the burst of %d", n, scope, key, b.limiter.Burst())}
+	}
+	wait := r.DelayFrom(now)
+	if wait > 0 {
+		r.CancelAt(now)
+		metrics.RateLimitedTotal.WithLabelValues(scope).Inc()
+		return wait, logs.RateLimited{Message: fmt.Sprintf("too many requests from %s %s", scope, key)}
+	}
+
+	return 0, nil
+}
+
+func (l *RateLimiter) strike(scope, key string) {
+	if l.cfg.BanThreshold <= 0 || key == "" {
+		return
+	}
+
+	now := time.Now()
+	id := scope + ":" + key
+
+	l.lk.Lock()
+	defer l.lk.Unlock()
+
+	s, ok := l.strikes[id]
+	if !ok || now.Sub(s.since) > l.cfg.BanWindow {
+		s = &strikes{since: now}
+		l.strikes[id] = s
+	}
+	s.count++
+
+	if s.count < l.cfg.BanThreshold {
+		return
+	}
+
+	delete(l.strikes, id)
+	l.bans[id] = now.Add(l.cfg.BanDuration)
+	metrics.BansTotal.WithLabelValues(scope).Inc()
+	logger.Warnf("ban %s %s for %s after %d invalid proofs", scope, key, l.cfg.BanDuration, s.count)
+}
+
+// drop idle buckets, old strikes and expired bans, called with lk held
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < limiterSweep {
+		return
+	}
+	l.swept = now
+
+	for id, b := range l.buckets {
+		if now.Sub(b.seen) > limiterIdle {
+			delete(l.buckets, id)
+		}
+	}
+	for id, s := range l.strikes {
+		if now.Sub(s.since) > l.cfg.BanWindow {
+			delete(l.strikes, id)
+		}
+	}
+	for id, until := range l.bans {
+		if now.After(until) {
+			delete(l.bans, id)
+		}
+	}
+}
+
+func setRetryAfter(c *gin.Context, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", fmt.Sprint(max(seconds, 1)))
+}
+
+func normalizeProvider(provider string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(provider, "0x"), "0X"))
+}
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"github.com/gridprotocol/validator/logs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// only let requests with a verified client certificate through
+func RequireClientCert() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		state := c.Request.TLS
+		if state == nil || len(state.VerifiedChains) == 0 {
+			AbortWithError(c, logs.AuthenticationFailed{Message: "a verified client certificate is required"})
+			return
+		}
+
+		c.Next()
+	}
+}
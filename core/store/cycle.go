@@ -0,0 +1,49 @@
+package store
+
+import (
+	"time"
+)
+
+// status of a cycle
+const (
+	// settled by the validator loop with the received proofs
+	CycleSettled = "settled"
+	// missed during downtime and settled by the recover policy
+	CycleRecovered = "recovered"
+	// missed during downtime and left unsettled
+	CycleSkipped = "skipped"
+)
+
+// a challenge cycle handled by the validator
+type Cycle struct {
+	// cycle start time / cycle length
+	ID         int64 `gorm:"primaryKey;autoIncrement:false"`
+	StartTime  time.Time
+	Status     string
+	Policy     string
+	Challenged int
+	Passed     int
+	Failed     int
+	SettledAt  time.Time
+}
+
+// get the latest handled cycle
+func (t tables) GetLastCycle() (Cycle, error) {
+	var cycle Cycle
+	err := t.db.Order("id desc").First(&cycle).Error
+	if err != nil {
+		return Cycle{}, notExist(err)
+	}
+
+	return cycle, nil
+}
+
+func (t tables) GetCycleByID(id int64) (Cycle, error) {
+	var cycle Cycle
+	err := t.db.Where("id = ?", id).First(&cycle).Error
+	if err != nil {
+		return Cycle{}, notExist(err)
+	}
+
+	return cycle, nil
+}
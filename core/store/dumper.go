@@ -0,0 +1,94 @@
+package store
+
+import (
+	"context"
+
+	"github.com/gridprotocol/dumper/database"
+
+	"golang.org/x/xerrors"
+)
+
+// storage of the validator on the dumper's sqlite database for orders and profits,
+// and the validator database for its own tables. both must be initialized before use
+type DumperStore struct {
+	tables
+}
+
+func NewDumperStore() *DumperStore {
+	return &DumperStore{tables{GlobalDataBase}}
+}
+
+// check both the validator db and the dumper db are reachable
+func (s *DumperStore) Ping(ctx context.Context) error {
+	err := s.tables.Ping(ctx)
+	if err != nil {
+		return xerrors.Errorf("validator db: %w", err)
+	}
+
+	return pingDumper()
+}
+
+// the dumper does not expose its connection, a cheap query checks its db answers
+func pingDumper() error {
+	_, err := database.GetOrderCount("")
+	if err != nil {
+		return xerrors.Errorf("dumper db: %w", err)
+	}
+
+	return nil
+}
+
+func (s *DumperStore) ListActiveOrders() ([]Order, error) {
+	orders, err := database.ListAllActivedOrder()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]Order, 0, len(orders))
+	for _, order := range orders {
+		res = append(res, Order{
+			ID:           order.Id,
+			Provider:     order.Provider,
+			ActivateTime: order.ActivateTime,
+			Probation:    int64(order.Probation),
+			Duration:     int64(order.Duration),
+		})
+	}
+
+	return res, nil
+}
+
+func (s *DumperStore) GetOrderCount(provider string) (int64, error) {
+	return database.GetOrderCount(provider)
+}
+
+func (s *DumperStore) GetProfit(provider string) (Profit, error) {
+	profit, err := database.GetProfitByAddress(provider)
+	if err != nil {
+		return Profit{}, notExist(err)
+	}
+
+	return Profit{
+		Address:  provider,
+		Balance:  profit.Balance,
+		Profit:   profit.Profit,
+		Penalty:  profit.Penalty,
+		Nonce:    profit.Nonce,
+		LastTime: profit.LastTime,
+		EndTime:  profit.EndTime,
+	}, nil
+}
+
+// write the settled amounts, the fields set on chain are left to the dumper
+func (s *DumperStore) UpdateProfit(profit Profit) error {
+	record, err := database.GetProfitByAddress(profit.Address)
+	if err != nil {
+		return notExist(err)
+	}
+
+	record.Balance = profit.Balance
+	record.Profit = profit.Profit
+	record.Penalty = profit.Penalty
+	record.LastTime = profit.LastTime
+	return record.UpdateProfit()
+}
@@ -0,0 +1,124 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// records read per query of an export, each batch is a short read so the validator is not blocked
+const exportBatch = 1000
+
+// cycles in [FromCycle, ToCycle] of an export, a 0 bound is open.
+// Provider limits proof results and ledger entries to one provider
+type ExportFilter struct {
+	FromCycle int64
+	ToCycle   int64
+	// cycles started in [From, To), a zero bound is open.
+	// ids of manual and recovered cycles do not follow their start time, so it is matched on the cycle records
+	From time.Time
+	To   time.Time
+
+	Provider string
+}
+
+// reads the validator tables in id order for exports, fn is called with each record
+type Exporter interface {
+	EachCycle(ctx context.Context, filter ExportFilter, fn func(Cycle) error) error
+	EachNodeResult(ctx context.Context, filter ExportFilter, fn func(NodeResult) error) error
+	EachLedger(ctx context.Context, filter ExportFilter, fn func(Ledger) error) error
+}
+
+// exports of the validator tables in db, e.g. of a stopped validator
+func NewExporter(db *gorm.DB) Exporter {
+	return tables{db}
+}
+
+// match the filter on a table with a cycle column, column is the id of the cycles table itself
+func (f ExportFilter) where(db *gorm.DB, column string, provider bool) *gorm.DB {
+	if f.FromCycle > 0 {
+		db = db.Where(column+" >= ?", f.FromCycle)
+	}
+	if f.ToCycle > 0 {
+		db = db.Where(column+" <= ?", f.ToCycle)
+	}
+	if provider && f.Provider != "" {
+		db = db.Where("provider = ?", f.Provider)
+	}
+
+	if f.From.IsZero() && f.To.IsZero() {
+		return db
+	}
+
+	cycles := db
+	if column != "id" {
+		cycles = db.Session(&gorm.Session{NewDB: true}).Model(&Cycle{}).Select("id")
+	}
+	// sqlite keeps the times as text with the offset they were written in, they are compared as unix seconds
+	start, from, to := "start_time", any(f.From), any(f.To)
+	if db.Dialector.Name() == BackendSQLite {
+		start, from, to = "unixepoch(start_time)", f.From.Unix(), f.To.Unix()
+	}
+	if !f.From.IsZero() {
+		cycles = cycles.Where(start+" >= ?", from)
+	}
+	if !f.To.IsZero() {
+		cycles = cycles.Where(start+" < ?", to)
+	}
+
+	if column == "id" {
+		return cycles
+	}
+	return db.Where(column+" IN (?)", cycles)
+}
+
+func (f ExportFilter) match(cycle Cycle) bool {
+	return (f.FromCycle <= 0 || cycle.ID >= f.FromCycle) &&
+		(f.ToCycle <= 0 || cycle.ID <= f.ToCycle) &&
+		(f.From.IsZero() || !cycle.StartTime.Before(f.From)) &&
+		(f.To.IsZero() || cycle.StartTime.Before(f.To))
+}
+
+// cycles are validator wide, the provider of the filter is ignored
+func (t tables) EachCycle(ctx context.Context, filter ExportFilter, fn func(Cycle) error) error {
+	var batch []Cycle
+	db := filter.where(t.db.WithContext(ctx), "id", false)
+	return db.FindInBatches(&batch, exportBatch, func(_ *gorm.DB, _ int) error {
+		for _, cycle := range batch {
+			err := fn(cycle)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
+
+func (t tables) EachNodeResult(ctx context.Context, filter ExportFilter, fn func(NodeResult) error) error {
+	var batch []NodeResult
+	db := filter.where(t.db.WithContext(ctx), "cycle", true)
+	return db.FindInBatches(&batch, exportBatch, func(_ *gorm.DB, _ int) error {
+		for _, result := range batch {
+			err := fn(result)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
+
+func (t tables) EachLedger(ctx context.Context, filter ExportFilter, fn func(Ledger) error) error {
+	var batch []Ledger
+	db := filter.where(t.db.WithContext(ctx), "cycle", true)
+	return db.FindInBatches(&batch, exportBatch, func(_ *gorm.DB, _ int) error {
+		for _, entry := range batch {
+			err := fn(entry)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
@@ -0,0 +1,104 @@
+package store
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// reason of a ledger entry
+const (
+	// settled after a challenge
+	ReasonChallenge = "challenge"
+	// settled by the recover policy, followed by the policy name
+	ReasonRecover = "recover-"
+)
+
+// profit change of a provider caused by settling one of its nodes,
+// amounts are decimal strings
+type Ledger struct {
+	ID       uint64 `gorm:"primaryKey"`
+	Cycle    int64  `gorm:"index"`
+	Provider string `gorm:"index"`
+	NodeID   uint64
+	Reason   string
+	Reward   string
+	Penalty  string
+	// balance and pending profit after settlement
+	Balance   string
+	Profit    string
+	CreatedAt time.Time
+}
+
+func (t tables) ListLedgerByCycle(cycle int64) ([]Ledger, error) {
+	var ledger []Ledger
+	err := t.db.Where("cycle = ?", cycle).Find(&ledger).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return ledger, nil
+}
+
+// changes of the validator to the profit of a provider, summed over its ledger entries
+type Settlement struct {
+	Provider string
+	Reward   *big.Int
+	Penalty  *big.Int
+	// start of the last settled cycle
+	LastTime time.Time
+}
+
+func newSettlement(provider string) *Settlement {
+	return &Settlement{Provider: provider, Reward: new(big.Int), Penalty: new(big.Int)}
+}
+
+// apply the settled changes onto a profit rebuilt from chain
+func (s Settlement) Apply(profit *Profit) {
+	profit.Balance = new(big.Int).Add(profit.Balance, s.Reward)
+	profit.Profit = new(big.Int).Sub(profit.Profit, s.Reward)
+	profit.Profit.Sub(profit.Profit, s.Penalty)
+	profit.Penalty = new(big.Int).Add(profit.Penalty, s.Penalty)
+	if s.LastTime.After(profit.LastTime) {
+		profit.LastTime = s.LastTime
+	}
+}
+
+// sum the ledger by provider, amounts are too large for sql sums
+func (t tables) SumLedgerByProvider(ctx context.Context) (map[string]*Settlement, error) {
+	var cycles []Cycle
+	err := t.db.WithContext(ctx).Select("id", "start_time").Find(&cycles).Error
+	if err != nil {
+		return nil, err
+	}
+	startTime := make(map[int64]time.Time, len(cycles))
+	for _, cycle := range cycles {
+		startTime[cycle.ID] = cycle.StartTime
+	}
+
+	res := make(map[string]*Settlement)
+	var batch []Ledger
+	err = t.db.WithContext(ctx).Select("id", "cycle", "provider", "reward", "penalty").FindInBatches(&batch, 1000, func(_ *gorm.DB, _ int) error {
+		for _, entry := range batch {
+			settled, ok := res[entry.Provider]
+			if !ok {
+				settled = newSettlement(entry.Provider)
+				res[entry.Provider] = settled
+			}
+
+			addDecimal(settled.Reward, entry.Reward)
+			addDecimal(settled.Penalty, entry.Penalty)
+			if startTime[entry.Cycle].After(settled.LastTime) {
+				settled.LastTime = startTime[entry.Cycle]
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
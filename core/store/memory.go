@@ -0,0 +1,292 @@
+package store
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/gridprotocol/validator/logs"
+)
+
+// storage of the validator in memory, for tests and trying the validator without a chain.
+// orders and profits are added with AddOrder and SetProfit instead of the dumper
+type MemoryStore struct {
+	lk sync.Mutex
+
+	orders  []Order
+	profits map[string]Profit
+	cycles  map[int64]Cycle
+	results []NodeResult
+	ledger  []Ledger
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		profits: make(map[string]Profit),
+		cycles:  make(map[int64]Cycle),
+	}
+}
+
+func (s *MemoryStore) AddOrder(order Order) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	s.orders = append(s.orders, order)
+}
+
+func (s *MemoryStore) SetProfit(profit Profit) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	s.profits[profit.Address] = copyProfit(profit)
+}
+
+func (s *MemoryStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (s *MemoryStore) ListActiveOrders() ([]Order, error) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	return append([]Order(nil), s.orders...), nil
+}
+
+func (s *MemoryStore) GetOrderCount(provider string) (int64, error) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	var count int64
+	for _, order := range s.orders {
+		if order.Provider == provider {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (s *MemoryStore) GetProfit(provider string) (Profit, error) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	profit, ok := s.profits[provider]
+	if !ok {
+		return Profit{}, logs.ErrNotExist
+	}
+
+	return copyProfit(profit), nil
+}
+
+// amounts are changed in place by the caller, so they are not shared
+func copyProfit(profit Profit) Profit {
+	for _, amount := range []**big.Int{&profit.Balance, &profit.Profit, &profit.Penalty} {
+		if *amount == nil {
+			*amount = new(big.Int)
+		} else {
+			*amount = new(big.Int).Set(*amount)
+		}
+	}
+	return profit
+}
+
+func (s *MemoryStore) GetLastCycle() (Cycle, error) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	var last *Cycle
+	for id := range s.cycles {
+		cycle := s.cycles[id]
+		if last == nil || cycle.ID > last.ID {
+			last = &cycle
+		}
+	}
+	if last == nil {
+		return Cycle{}, logs.ErrNotExist
+	}
+
+	return *last, nil
+}
+
+func (s *MemoryStore) ListNodeSummariesByProvider(provider string) ([]NodeSummary, error) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	nodes := make(map[uint64]*NodeSummary)
+	for _, result := range s.results {
+		if result.Provider != provider {
+			continue
+		}
+
+		summary, ok := nodes[result.NodeID]
+		if !ok {
+			summary = &NodeSummary{NodeID: result.NodeID, LastCycle: result.Cycle}
+			nodes[result.NodeID] = summary
+		}
+		if result.Passed {
+			summary.Passed++
+		} else {
+			summary.Failed++
+		}
+		if result.Cycle >= summary.LastCycle {
+			summary.LastCycle = result.Cycle
+			summary.LastPassed = result.Passed
+		}
+	}
+
+	summaries := make([]NodeSummary, 0, len(nodes))
+	for _, summary := range nodes {
+		summaries = append(summaries, *summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].NodeID < summaries[j].NodeID
+	})
+
+	return summaries, nil
+}
+
+func (s *MemoryStore) CountResults(fromCycle int64) (ResultCount, error) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	var count ResultCount
+	nodes := make(map[string]struct{})
+	for _, result := range s.results {
+		if result.Cycle < fromCycle {
+			continue
+		}
+
+		count.Challenges++
+		if result.Passed {
+			count.Passed++
+		}
+		nodes[result.Provider+":"+strconv.FormatUint(result.NodeID, 10)] = struct{}{}
+	}
+	count.Nodes = int64(len(nodes))
+
+	return count, nil
+}
+
+func (s *MemoryStore) ListTopFailing(fromCycle int64, limit int) ([]ProviderFailures, error) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	failed := make(map[string]int64)
+	for _, result := range s.results {
+		if result.Cycle >= fromCycle && !result.Passed {
+			failed[result.Provider]++
+		}
+	}
+
+	res := make([]ProviderFailures, 0, len(failed))
+	for provider, n := range failed {
+		res = append(res, ProviderFailures{Provider: provider, Failed: n})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Failed != res[j].Failed {
+			return res[i].Failed > res[j].Failed
+		}
+		return res[i].Provider < res[j].Provider
+	})
+	if len(res) > limit {
+		res = res[:limit]
+	}
+
+	return res, nil
+}
+
+func (s *MemoryStore) SumLedger() (*big.Int, *big.Int, error) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	reward := new(big.Int)
+	penalty := new(big.Int)
+	for _, entry := range s.ledger {
+		addDecimal(reward, entry.Reward)
+		addDecimal(penalty, entry.Penalty)
+	}
+
+	return reward, penalty, nil
+}
+
+// records are copied first, so fn may use the store
+func (s *MemoryStore) EachCycle(ctx context.Context, filter ExportFilter, fn func(Cycle) error) error {
+	s.lk.Lock()
+	var cycles []Cycle
+	for _, cycle := range s.cycles {
+		if filter.match(cycle) {
+			cycles = append(cycles, cycle)
+		}
+	}
+	s.lk.Unlock()
+
+	sort.Slice(cycles, func(i, j int) bool {
+		return cycles[i].ID < cycles[j].ID
+	})
+	for _, cycle := range cycles {
+		err := fn(cycle)
+		if err != nil {
+			return err
+		}
+	}
+
+	return ctx.Err()
+}
+
+// the record of a cycle must be in the cycles to match dates, like the sql filter
+func (s *MemoryStore) matchRecord(filter ExportFilter, cycle int64, provider string) bool {
+	if filter.Provider != "" && provider != filter.Provider {
+		return false
+	}
+
+	c, ok := s.cycles[cycle]
+	if !ok {
+		if !filter.From.IsZero() || !filter.To.IsZero() {
+			return false
+		}
+		c = Cycle{ID: cycle}
+	}
+	return filter.match(c)
+}
+
+func (s *MemoryStore) EachNodeResult(ctx context.Context, filter ExportFilter, fn func(NodeResult) error) error {
+	s.lk.Lock()
+	var results []NodeResult
+	for _, result := range s.results {
+		if s.matchRecord(filter, result.Cycle, result.Provider) {
+			results = append(results, result)
+		}
+	}
+	s.lk.Unlock()
+
+	for _, result := range results {
+		err := fn(result)
+		if err != nil {
+			return err
+		}
+	}
+
+	return ctx.Err()
+}
+
+func (s *MemoryStore) EachLedger(ctx context.Context, filter ExportFilter, fn func(Ledger) error) error {
+	s.lk.Lock()
+	var ledger []Ledger
+	for _, entry := range s.ledger {
+		if s.matchRecord(filter, entry.Cycle, entry.Provider) {
+			ledger = append(ledger, entry)
+		}
+	}
+	s.lk.Unlock()
+
+	for _, entry := range ledger {
+		err := fn(entry)
+		if err != nil {
+			return err
+		}
+	}
+
+	return ctx.Err()
+}
@@ -0,0 +1,378 @@
+package store
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+	"gorm.io/gorm"
+)
+
+// schema of each backend, migrations/<backend>/<version>_<name>.up.sql
+// and the optional <version>_<name>.down.sql reverting it
+//
+//go:embed migrations
+var migrationFS embed.FS
+
+type migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// applied migrations
+type schemaMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// key of the postgres advisory lock held while migrating
+const migrationLock = 0x67726964
+
+// migrations of a backend in version order
+func loadMigrations(backend string) ([]migration, error) {
+	dir := path.Join("migrations", backend)
+	files, err := fs.Glob(migrationFS, path.Join(dir, "*.up.sql"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, xerrors.Errorf("no migrations for db backend %q", backend)
+	}
+
+	var migrations []migration
+	for _, file := range files {
+		base := strings.TrimSuffix(path.Base(file), ".up.sql")
+		version, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, xerrors.Errorf("migration %s is not named <version>_<name>.up.sql", file)
+		}
+		v, err := strconv.Atoi(version)
+		if err != nil || v <= 0 {
+			return nil, xerrors.Errorf("migration %s has an invalid version", file)
+		}
+
+		up, err := migrationFS.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		down, err := migrationFS.ReadFile(path.Join(dir, base+".down.sql"))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+
+		migrations = append(migrations, migration{Version: v, Name: name, Up: string(up), Down: string(down)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, xerrors.Errorf("duplicate migration version %d", migrations[i].Version)
+		}
+	}
+
+	return migrations, nil
+}
+
+// state of a migration known by this binary, or applied by a newer one
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	// applied but unknown to this binary
+	Unknown bool
+}
+
+// applies and reverts the numbered migrations of a backend
+type Migrator struct {
+	db         *gorm.DB
+	backend    string
+	migrations []migration
+}
+
+func NewMigrator(db *gorm.DB, backend string) (*Migrator, error) {
+	migrations, err := loadMigrations(backend)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		backend:    backend,
+		migrations: migrations,
+	}, nil
+}
+
+// latest schema version known by this binary
+func (m *Migrator) Latest() int {
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// applied migrations by version
+func (m *Migrator) applied(tx *gorm.DB) (map[int]schemaMigration, error) {
+	res := make(map[int]schemaMigration)
+	if !tx.Migrator().HasTable(&schemaMigration{}) {
+		return res, nil
+	}
+
+	var applied []schemaMigration
+	err := tx.Find(&applied).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range applied {
+		res[a.Version] = a
+	}
+
+	return res, nil
+}
+
+// refuse a schema migrated by a newer binary, it may not work with this one
+func (m *Migrator) checkNewer(applied map[int]schemaMigration) error {
+	latest := m.Latest()
+	for version := range applied {
+		if version > latest {
+			return xerrors.Errorf("db schema version %d is newer than version %d of this binary, upgrade the validator or roll back with the newer binary", version, latest)
+		}
+	}
+
+	return nil
+}
+
+// known and applied migrations in version order
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.applied(m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []MigrationStatus
+	for _, mg := range m.migrations {
+		a, ok := applied[mg.Version]
+		res = append(res, MigrationStatus{
+			Version:   mg.Version,
+			Name:      mg.Name,
+			Applied:   ok,
+			AppliedAt: a.AppliedAt,
+		})
+		delete(applied, mg.Version)
+	}
+	for _, a := range applied {
+		res = append(res, MigrationStatus{
+			Version:   a.Version,
+			Name:      a.Name,
+			Applied:   true,
+			AppliedAt: a.AppliedAt,
+			Unknown:   true,
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Version < res[j].Version
+	})
+
+	return res, nil
+}
+
+// check the schema is at the version of this binary
+func (m *Migrator) Check() error {
+	applied, err := m.applied(m.db)
+	if err != nil {
+		return err
+	}
+
+	err = m.checkNewer(applied)
+	if err != nil {
+		return err
+	}
+
+	pending := 0
+	for _, mg := range m.migrations {
+		if _, ok := applied[mg.Version]; !ok {
+			pending++
+		}
+	}
+	if pending > 0 {
+		return xerrors.Errorf("%d db migrations are pending, apply them with validator db migrate", pending)
+	}
+
+	return nil
+}
+
+// apply the pending migrations up to version target, 0 means the latest.
+// they are applied in one transaction, so a failed one leaves the schema as it was
+func (m *Migrator) Migrate(target int) ([]MigrationStatus, error) {
+	if target == 0 {
+		target = m.Latest()
+	}
+
+	var res []MigrationStatus
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		err := m.lock(tx)
+		if err != nil {
+			return err
+		}
+
+		err = tx.AutoMigrate(&schemaMigration{})
+		if err != nil {
+			return err
+		}
+
+		applied, err := m.applied(tx)
+		if err != nil {
+			return err
+		}
+		err = m.checkNewer(applied)
+		if err != nil {
+			return err
+		}
+
+		for _, mg := range m.migrations {
+			if mg.Version > target {
+				break
+			}
+			if _, ok := applied[mg.Version]; ok {
+				continue
+			}
+
+			logger.Infof("apply migration %d %s", mg.Version, mg.Name)
+			err = tx.Exec(mg.Up).Error
+			if err != nil {
+				return xerrors.Errorf("migration %d %s: %w", mg.Version, mg.Name, err)
+			}
+
+			a := schemaMigration{Version: mg.Version, Name: mg.Name, AppliedAt: time.Now()}
+			err = tx.Create(&a).Error
+			if err != nil {
+				return err
+			}
+			res = append(res, MigrationStatus{Version: a.Version, Name: a.Name, Applied: true, AppliedAt: a.AppliedAt})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// revert the latest steps applied migrations in one transaction.
+// a migration dropping a table that has rows is only reverted with force
+func (m *Migrator) Rollback(steps int, force bool) ([]MigrationStatus, error) {
+	var res []MigrationStatus
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		err := m.lock(tx)
+		if err != nil {
+			return err
+		}
+
+		applied, err := m.applied(tx)
+		if err != nil {
+			return err
+		}
+		err = m.checkNewer(applied)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(res) < steps; i-- {
+			mg := m.migrations[i]
+			if _, ok := applied[mg.Version]; !ok {
+				continue
+			}
+			if mg.Down == "" {
+				return xerrors.Errorf("migration %d %s can not be rolled back", mg.Version, mg.Name)
+			}
+			if !force {
+				err = checkDropped(tx, mg)
+				if err != nil {
+					return err
+				}
+			}
+
+			logger.Infof("roll back migration %d %s", mg.Version, mg.Name)
+			err = tx.Exec(mg.Down).Error
+			if err != nil {
+				return xerrors.Errorf("roll back migration %d %s: %w", mg.Version, mg.Name, err)
+			}
+
+			err = tx.Delete(&schemaMigration{}, mg.Version).Error
+			if err != nil {
+				return err
+			}
+			res = append(res, MigrationStatus{Version: mg.Version, Name: mg.Name})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+var dropTable = regexp.MustCompile("(?i)DROP\\s+TABLE\\s+(?:IF\\s+EXISTS\\s+)?[`\"]?(\\w+)")
+
+// refuse to drop the records of the tables a down migration drops
+func checkDropped(tx *gorm.DB, mg migration) error {
+	for _, match := range dropTable.FindAllStringSubmatch(mg.Down, -1) {
+		table := match[1]
+		if !tx.Migrator().HasTable(table) {
+			continue
+		}
+
+		var count int64
+		err := tx.Table(table).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return xerrors.Errorf("rolling back migration %d %s drops table %s with %d rows, force it to lose them", mg.Version, mg.Name, table, count)
+		}
+	}
+
+	return nil
+}
+
+// validators sharing a postgres database may migrate together,
+// sqlite serializes the write transactions itself
+func (m *Migrator) lock(tx *gorm.DB) error {
+	if m.backend != BackendPostgres {
+		return nil
+	}
+
+	return tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationLock).Error
+}
+
+// check the schema before the validator uses it, pending migrations are applied with autoMigrate
+func prepareSchema(db *gorm.DB, backend string, autoMigrate bool) error {
+	m, err := NewMigrator(db, backend)
+	if err != nil {
+		return err
+	}
+
+	if autoMigrate {
+		_, err = m.Migrate(0)
+		return err
+	}
+
+	return m.Check()
+}
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS ledgers;
+DROP TABLE IF EXISTS node_results;
+DROP TABLE IF EXISTS cycles;
+DROP TABLE IF EXISTS profits;
+DROP TABLE IF EXISTS orders;
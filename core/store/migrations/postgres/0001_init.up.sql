@@ -0,0 +1,55 @@
+-- active orders and profits mirrored from the dumper
+CREATE TABLE orders (
+    id            BIGINT PRIMARY KEY,
+    provider      TEXT NOT NULL,
+    activate_time TIMESTAMPTZ NOT NULL,
+    probation     BIGINT NOT NULL DEFAULT 0,
+    duration      BIGINT NOT NULL DEFAULT 0
+);
+CREATE INDEX idx_orders_provider ON orders (provider);
+
+CREATE TABLE profits (
+    address   TEXT PRIMARY KEY,
+    balance   NUMERIC(78, 0) NOT NULL DEFAULT 0,
+    profit    NUMERIC(78, 0) NOT NULL DEFAULT 0,
+    penalty   NUMERIC(78, 0) NOT NULL DEFAULT 0,
+    nonce     BIGINT NOT NULL DEFAULT 0,
+    last_time TIMESTAMPTZ NOT NULL,
+    end_time  TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE cycles (
+    id         BIGINT PRIMARY KEY,
+    start_time TIMESTAMPTZ NOT NULL,
+    status     TEXT NOT NULL,
+    policy     TEXT NOT NULL DEFAULT '',
+    challenged INTEGER NOT NULL DEFAULT 0,
+    passed     INTEGER NOT NULL DEFAULT 0,
+    failed     INTEGER NOT NULL DEFAULT 0,
+    settled_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE node_results (
+    id       BIGSERIAL PRIMARY KEY,
+    cycle    BIGINT NOT NULL,
+    provider TEXT NOT NULL,
+    node_id  BIGINT NOT NULL,
+    passed   BOOLEAN NOT NULL
+);
+CREATE INDEX idx_node_results_cycle ON node_results (cycle);
+CREATE INDEX idx_node_results_provider ON node_results (provider);
+
+CREATE TABLE ledgers (
+    id         BIGSERIAL PRIMARY KEY,
+    cycle      BIGINT NOT NULL,
+    provider   TEXT NOT NULL,
+    node_id    BIGINT NOT NULL,
+    reason     TEXT NOT NULL,
+    reward     TEXT NOT NULL,
+    penalty    TEXT NOT NULL,
+    balance    TEXT NOT NULL,
+    profit     TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_ledgers_cycle ON ledgers (cycle);
+CREATE INDEX idx_ledgers_provider ON ledgers (provider);
@@ -0,0 +1,6 @@
+-- the chain block the dumper db is synced to, one row
+CREATE TABLE sync_states (
+    id           INTEGER PRIMARY KEY,
+    synced_block BIGINT NOT NULL,
+    updated_at   TIMESTAMPTZ NOT NULL
+);
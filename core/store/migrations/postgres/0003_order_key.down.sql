@@ -0,0 +1,5 @@
+-- orders of providers sharing an id do not fit the old key, they are imported again from the dumper
+DELETE FROM orders;
+ALTER TABLE orders DROP CONSTRAINT orders_pkey;
+ALTER TABLE orders ADD PRIMARY KEY (id);
+CREATE INDEX idx_orders_provider ON orders (provider);
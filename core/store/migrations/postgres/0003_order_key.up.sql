@@ -0,0 +1,5 @@
+-- a node id is only unique within its provider, so orders are keyed by both
+ALTER TABLE orders DROP CONSTRAINT orders_pkey;
+ALTER TABLE orders ADD PRIMARY KEY (provider, id);
+-- the key leads with the provider
+DROP INDEX IF EXISTS idx_orders_provider;
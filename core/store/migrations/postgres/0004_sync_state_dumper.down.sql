@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS sync_states;
+CREATE TABLE sync_states (
+    id           INTEGER PRIMARY KEY,
+    synced_block BIGINT NOT NULL,
+    updated_at   TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,8 @@
+-- validators sharing the database each sync their own dumper db, so the block is kept per dumper db.
+-- the block of the single row is not known to be of any of them, it is measured again
+DROP TABLE sync_states;
+CREATE TABLE sync_states (
+    dumper       TEXT PRIMARY KEY,
+    synced_block BIGINT NOT NULL,
+    updated_at   TIMESTAMPTZ NOT NULL
+);
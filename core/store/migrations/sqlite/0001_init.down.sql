@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS `ledgers`;
+DROP TABLE IF EXISTS `node_results`;
+DROP TABLE IF EXISTS `cycles`;
@@ -0,0 +1,37 @@
+-- same as the tables created by gorm before migrations, so existing databases are adopted
+CREATE TABLE IF NOT EXISTS `cycles` (
+    `id`         integer,
+    `start_time` datetime,
+    `status`     text,
+    `policy`     text,
+    `challenged` integer,
+    `passed`     integer,
+    `failed`     integer,
+    `settled_at` datetime,
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `node_results` (
+    `id`       integer PRIMARY KEY AUTOINCREMENT,
+    `cycle`    integer,
+    `provider` text,
+    `node_id`  integer,
+    `passed`   numeric
+);
+CREATE INDEX IF NOT EXISTS `idx_node_results_provider` ON `node_results`(`provider`);
+CREATE INDEX IF NOT EXISTS `idx_node_results_cycle` ON `node_results`(`cycle`);
+
+CREATE TABLE IF NOT EXISTS `ledgers` (
+    `id`         integer PRIMARY KEY AUTOINCREMENT,
+    `cycle`      integer,
+    `provider`   text,
+    `node_id`    integer,
+    `reason`     text,
+    `reward`     text,
+    `penalty`    text,
+    `balance`    text,
+    `profit`     text,
+    `created_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_ledgers_cycle` ON `ledgers`(`cycle`);
+CREATE INDEX IF NOT EXISTS `idx_ledgers_provider` ON `ledgers`(`provider`);
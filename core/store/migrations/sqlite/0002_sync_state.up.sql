@@ -0,0 +1,7 @@
+-- the chain block the dumper db is synced to, one row
+CREATE TABLE `sync_states` (
+    `id`           integer,
+    `synced_block` integer NOT NULL,
+    `updated_at`   datetime NOT NULL,
+    PRIMARY KEY (`id`)
+);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS `sync_states`;
+CREATE TABLE `sync_states` (
+    `id`           integer,
+    `synced_block` integer NOT NULL,
+    `updated_at`   datetime NOT NULL,
+    PRIMARY KEY (`id`)
+);
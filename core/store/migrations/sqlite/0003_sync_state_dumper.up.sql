@@ -0,0 +1,8 @@
+-- the block is kept per dumper db as in postgres, the single row is measured again
+DROP TABLE `sync_states`;
+CREATE TABLE `sync_states` (
+    `dumper`       text,
+    `synced_block` integer NOT NULL,
+    `updated_at`   datetime NOT NULL,
+    PRIMARY KEY (`dumper`)
+);
@@ -0,0 +1,28 @@
+package store
+
+import (
+	"math/big"
+	"time"
+)
+
+// active order of a node, synced from chain by the dumper
+type Order struct {
+	// node ids are only unique within a provider, orders are keyed by both
+	ID           uint64 `gorm:"primaryKey;autoIncrement:false"`
+	Provider     string `gorm:"primaryKey"`
+	ActivateTime time.Time
+	Probation    int64
+	Duration     int64
+}
+
+// profit of a provider, the amounts are changed in place when a node is settled
+type Profit struct {
+	Address string
+	Balance *big.Int
+	Profit  *big.Int
+	Penalty *big.Int
+	// withdraw nonce, set on chain
+	Nonce    uint64
+	LastTime time.Time
+	EndTime  time.Time
+}
@@ -0,0 +1,47 @@
+package store
+
+// proof result of a challenged node in a cycle
+type NodeResult struct {
+	ID       uint64 `gorm:"primaryKey"`
+	Cycle    int64  `gorm:"index"`
+	Provider string `gorm:"index"`
+	NodeID   uint64
+	Passed   bool
+}
+
+func (t tables) ListNodeResultsByCycle(cycle int64) ([]NodeResult, error) {
+	var results []NodeResult
+	err := t.db.Where("cycle = ?", cycle).Find(&results).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// proof results of a node summed over cycles
+type NodeSummary struct {
+	NodeID    uint64
+	Passed    int
+	Failed    int
+	LastCycle int64
+	// result of the last cycle
+	LastPassed bool
+}
+
+// summaries of the nodes of a provider with their last result, in one query
+func (t tables) ListNodeSummariesByProvider(provider string) ([]NodeSummary, error) {
+	var summaries []NodeSummary
+	err := t.db.Raw(`SELECT s.node_id, s.passed, s.failed, s.last_cycle, r.passed AS last_passed
+FROM (
+	SELECT node_id, SUM(CASE WHEN passed THEN 1 ELSE 0 END) AS passed, SUM(CASE WHEN passed THEN 0 ELSE 1 END) AS failed, MAX(cycle) AS last_cycle
+	FROM node_results WHERE provider = ? GROUP BY node_id
+) s
+JOIN node_results r ON r.provider = ? AND r.node_id = s.node_id AND r.cycle = s.last_cycle
+ORDER BY s.node_id`, provider, provider).Scan(&summaries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return summaries, nil
+}
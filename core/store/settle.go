@@ -0,0 +1,256 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/gridprotocol/validator/logs"
+
+	"golang.org/x/xerrors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// profits read by a settlement to compute its ledger, in postgres they are locked until it is written
+type ProfitReader interface {
+	GetProfit(provider string) (Profit, error)
+}
+
+// computes the ledger entries of a cycle from the profits
+type SettleFunc func(profits ProfitReader) ([]Ledger, error)
+
+// insert the cycle first, so a validator settling it at the same time waits for this one,
+// fails with logs.ErrAlreadyExist if the cycle is recorded
+func claimCycle(tx *gorm.DB, cycle *Cycle) error {
+	res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(cycle)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return xerrors.Errorf("cycle %d: %w", cycle.ID, logs.ErrAlreadyExist)
+	}
+
+	return nil
+}
+
+// record the node results and ledger entries of a claimed cycle in tx
+func recordResults(tx *gorm.DB, results []NodeResult, ledger []Ledger) error {
+	if len(results) > 0 {
+		err := tx.CreateInBatches(results, 500).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(ledger) > 0 {
+		err := tx.CreateInBatches(ledger, 500).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// changes of the ledger entries of a cycle by provider, in the order of providers
+func cycleSettlements(cycle Cycle, ledger []Ledger) []*Settlement {
+	byProvider := make(map[string]*Settlement)
+	for _, entry := range ledger {
+		settled, ok := byProvider[entry.Provider]
+		if !ok {
+			settled = newSettlement(entry.Provider)
+			settled.LastTime = cycle.StartTime
+			byProvider[entry.Provider] = settled
+		}
+
+		addDecimal(settled.Reward, entry.Reward)
+		addDecimal(settled.Penalty, entry.Penalty)
+	}
+
+	res := make([]*Settlement, 0, len(byProvider))
+	for _, settled := range byProvider {
+		res = append(res, settled)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Provider < res[j].Provider
+	})
+
+	return res
+}
+
+// the profits are in the dumper database, so they are written after the cycle is recorded
+// in the validator database. the ledger of the last cycle is applied again before the next
+// cycle is recorded, a profit settled up to the cycle already has its changes
+func (s *DumperStore) SettleCycle(ctx context.Context, cycle *Cycle, results []NodeResult, settle SettleFunc) error {
+	err := s.ResumeSettlement()
+	if err != nil {
+		return err
+	}
+
+	ledger, err := settle(s)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := claimCycle(tx, cycle)
+		if err != nil {
+			return err
+		}
+
+		return recordResults(tx, results, ledger)
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.applyLedger(*cycle, ledger)
+}
+
+// write the profits of the last recorded cycle, if the validator stopped before they were written
+func (s *DumperStore) ResumeSettlement() error {
+	last, err := s.GetLastCycle()
+	if err != nil {
+		if errors.Is(err, logs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	ledger, err := s.ListLedgerByCycle(last.ID)
+	if err != nil {
+		return err
+	}
+
+	return s.applyLedger(last, ledger)
+}
+
+func (s *DumperStore) applyLedger(cycle Cycle, ledger []Ledger) error {
+	for _, settled := range cycleSettlements(cycle, ledger) {
+		profit, err := s.GetProfit(settled.Provider)
+		if err != nil {
+			return xerrors.Errorf("profit of %s: %w", settled.Provider, err)
+		}
+		if !profit.LastTime.Before(cycle.StartTime) {
+			continue
+		}
+
+		settled.Apply(&profit)
+		err = s.UpdateProfit(profit)
+		if err != nil {
+			return xerrors.Errorf("settle profit of %s in cycle %d: %w", settled.Provider, cycle.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// the cycle is claimed, the profits are read and locked, and the cycle, its results and ledger
+// and the profit changes are written in one transaction. validators sharing the database
+// settle a cycle once, the others fail with logs.ErrAlreadyExist
+func (s *PostgresStore) SettleCycle(ctx context.Context, cycle *Cycle, results []NodeResult, settle SettleFunc) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := claimCycle(tx, cycle)
+		if err != nil {
+			return err
+		}
+
+		ledger, err := settle(lockedProfits{tx})
+		if err != nil {
+			return err
+		}
+
+		err = recordResults(tx, results, ledger)
+		if err != nil {
+			return err
+		}
+
+		for _, settled := range cycleSettlements(*cycle, ledger) {
+			res := tx.Exec(`UPDATE profits SET
+				balance = balance + CAST(? AS NUMERIC),
+				profit = profit - CAST(? AS NUMERIC) - CAST(? AS NUMERIC),
+				penalty = penalty + CAST(? AS NUMERIC),
+				last_time = GREATEST(last_time, ?)
+				WHERE address = ?`,
+				settled.Reward.String(), settled.Reward.String(), settled.Penalty.String(), settled.Penalty.String(),
+				settled.LastTime, settled.Provider)
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return xerrors.Errorf("profit of %s: %w", settled.Provider, logs.ErrNotExist)
+			}
+		}
+
+		return nil
+	})
+}
+
+// profits of a postgres settlement, locked until its transaction ends
+type lockedProfits struct {
+	tx *gorm.DB
+}
+
+func (p lockedProfits) GetProfit(provider string) (Profit, error) {
+	return getProfit(p.tx.Clauses(clause.Locking{Strength: "UPDATE"}), provider)
+}
+
+// nothing is written if the cycle exists or a profit is missing
+func (s *MemoryStore) SettleCycle(ctx context.Context, cycle *Cycle, results []NodeResult, settle SettleFunc) error {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	_, ok := s.cycles[cycle.ID]
+	if ok {
+		return xerrors.Errorf("cycle %d: %w", cycle.ID, logs.ErrAlreadyExist)
+	}
+
+	ledger, err := settle(memoryProfits{s})
+	if err != nil {
+		return err
+	}
+
+	settlements := cycleSettlements(*cycle, ledger)
+	for _, settled := range settlements {
+		_, ok := s.profits[settled.Provider]
+		if !ok {
+			return xerrors.Errorf("profit of %s: %w", settled.Provider, logs.ErrNotExist)
+		}
+	}
+
+	s.cycles[cycle.ID] = *cycle
+	for i := range results {
+		results[i].ID = uint64(len(s.results)) + 1
+		s.results = append(s.results, results[i])
+	}
+	for i := range ledger {
+		ledger[i].ID = uint64(len(s.ledger)) + 1
+		if ledger[i].CreatedAt.IsZero() {
+			ledger[i].CreatedAt = time.Now()
+		}
+		s.ledger = append(s.ledger, ledger[i])
+	}
+	for _, settled := range settlements {
+		profit := copyProfit(s.profits[settled.Provider])
+		settled.Apply(&profit)
+		s.profits[settled.Provider] = profit
+	}
+
+	return nil
+}
+
+// profits of a memory settlement, the store is locked by it
+type memoryProfits struct {
+	s *MemoryStore
+}
+
+func (p memoryProfits) GetProfit(provider string) (Profit, error) {
+	profit, ok := p.s.profits[provider]
+	if !ok {
+		return Profit{}, logs.ErrNotExist
+	}
+
+	return copyProfit(profit), nil
+}
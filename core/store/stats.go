@@ -0,0 +1,76 @@
+package store
+
+import (
+	"math/big"
+
+	"gorm.io/gorm"
+)
+
+// proof results of the cycles since fromCycle
+type ResultCount struct {
+	Challenges int64
+	Passed     int64
+	Nodes      int64
+}
+
+func (t tables) CountResults(fromCycle int64) (ResultCount, error) {
+	var count ResultCount
+	err := t.db.Model(&NodeResult{}).
+		Select("COUNT(*) AS challenges, COALESCE(SUM(CASE WHEN passed THEN 1 ELSE 0 END), 0) AS passed, COUNT(DISTINCT provider || ':' || node_id) AS nodes").
+		Where("cycle >= ?", fromCycle).
+		Scan(&count).Error
+	if err != nil {
+		return ResultCount{}, err
+	}
+
+	return count, nil
+}
+
+type ProviderFailures struct {
+	Provider string
+	Failed   int64
+}
+
+// providers with the most failed proofs since fromCycle
+func (t tables) ListTopFailing(fromCycle int64, limit int) ([]ProviderFailures, error) {
+	var res []ProviderFailures
+	err := t.db.Model(&NodeResult{}).
+		Select("provider, COUNT(*) AS failed").
+		Where("cycle >= ? AND passed = ?", fromCycle, false).
+		Group("provider").
+		Order("failed desc").
+		Limit(limit).
+		Scan(&res).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// sum reward and penalty of all ledger entries, amounts are too large for sql sums
+func (t tables) SumLedger() (*big.Int, *big.Int, error) {
+	reward := new(big.Int)
+	penalty := new(big.Int)
+
+	var batch []Ledger
+	err := t.db.Select("id", "reward", "penalty").FindInBatches(&batch, 1000, func(_ *gorm.DB, _ int) error {
+		for _, entry := range batch {
+			addDecimal(reward, entry.Reward)
+			addDecimal(penalty, entry.Penalty)
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return reward, penalty, nil
+}
+
+func addDecimal(sum *big.Int, amount string) {
+	value, ok := new(big.Int).SetString(amount, 10)
+	if ok {
+		sum.Add(sum, value)
+	}
+}
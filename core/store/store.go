@@ -0,0 +1,117 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gridprotocol/validator/logs"
+
+	"github.com/mitchellh/go-homedir"
+	"golang.org/x/xerrors"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// validator owned tables live in their own file next to the dumper database
+const dbName = "validator.db"
+
+// storage backends
+const (
+	// validator.db next to the dumper database
+	BackendSQLite = "sqlite"
+	// postgres database shared by validators, orders and profits are imported from the dumper
+	BackendPostgres = "postgres"
+)
+
+var logger = logs.Logger("grid store")
+
+var GlobalDataBase *gorm.DB
+
+// open the validator database in path, its schema is checked and migrated with autoMigrate
+func InitStore(path string, autoMigrate bool) error {
+	db, err := OpenDB(BackendSQLite, path)
+	if err != nil {
+		return err
+	}
+
+	err = prepareSchema(db, BackendSQLite, autoMigrate)
+	if err != nil {
+		return err
+	}
+
+	GlobalDataBase = db
+	return nil
+}
+
+// open the database of a backend without touching its schema,
+// source is the directory of validator.db for sqlite or the connection string for postgres
+func OpenDB(backend, source string) (*gorm.DB, error) {
+	switch backend {
+	case BackendSQLite:
+		dir, err := homedir.Expand(source)
+		if err != nil {
+			return nil, err
+		}
+
+		err = os.MkdirAll(dir, 0750)
+		if err != nil {
+			return nil, err
+		}
+
+		// writes wait while a snapshot pauses them instead of failing
+		return gorm.Open(sqlite.Open(filepath.Join(dir, dbName)+"?_busy_timeout=30000"), gormConfig())
+	case BackendPostgres:
+		return gorm.Open(postgres.Open(source), gormConfig())
+	}
+
+	return nil, xerrors.Errorf("unknown db backend %q, expect sqlite or postgres", backend)
+}
+
+func CloseDB(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	return sqlDB.Close()
+}
+
+func gormConfig() *gorm.Config {
+	return &gorm.Config{
+		// missing rows are expected, e.g. no cycle settled yet
+		Logger: gormlogger.New(log.New(os.Stdout, "\r\n", log.LstdFlags), gormlogger.Config{
+			SlowThreshold:             200 * time.Millisecond,
+			LogLevel:                  gormlogger.Warn,
+			IgnoreRecordNotFoundError: true,
+		}),
+	}
+}
+
+// validator owned tables on a gorm database, shared by the sqlite and postgres stores
+type tables struct {
+	db *gorm.DB
+}
+
+// check the db is reachable
+func (t tables) Ping(ctx context.Context) error {
+	db, err := t.db.DB()
+	if err != nil {
+		return err
+	}
+
+	return db.PingContext(ctx)
+}
+
+// convert gorm not found error into logs.ErrNotExist
+func notExist(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return logs.ErrNotExist
+	}
+	return err
+}
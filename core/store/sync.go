@@ -0,0 +1,68 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// the chain block a dumper db is synced to, a row per dumper db,
+// so validators sharing postgres each keep the block of their own
+type SyncState struct {
+	Dumper      string `gorm:"primaryKey"`
+	SyncedBlock uint64
+	UpdatedAt   time.Time
+}
+
+// name of the dumper db in dataDir on this host, e.g.(host1:/root/grid)
+func DumperName(dataDir string) (string, error) {
+	dir, err := homedir.Expand(dataDir)
+	if err != nil {
+		return "", err
+	}
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+
+	return host + ":" + dir, nil
+}
+
+// synced block of one dumper db
+type SyncedBlocks struct {
+	db     *gorm.DB
+	dumper string
+}
+
+// synced block of the dumper db named by DumperName
+func (t tables) SyncedBlocks(dumper string) *SyncedBlocks {
+	return &SyncedBlocks{db: t.db, dumper: dumper}
+}
+
+func (b *SyncedBlocks) GetSyncedBlock() (uint64, error) {
+	var state SyncState
+	err := b.db.Where("dumper = ?", b.dumper).First(&state).Error
+	if err != nil {
+		return 0, notExist(err)
+	}
+
+	return state.SyncedBlock, nil
+}
+
+func (b *SyncedBlocks) SetSyncedBlock(block uint64) error {
+	state := SyncState{
+		Dumper:      b.dumper,
+		SyncedBlock: block,
+		UpdatedAt:   time.Now(),
+	}
+	return b.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&state).Error
+}
@@ -0,0 +1,17 @@
+package syncer
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// report sync status, 503 if the db is stale
+func (s *Supervisor) StatusHandler(c *gin.Context) {
+	code := http.StatusOK
+	if s.Stale() {
+		code = http.StatusServiceUnavailable
+	}
+
+	c.JSON(code, s.Status())
+}
@@ -0,0 +1,356 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gridprotocol/validator/core/metrics"
+	"github.com/gridprotocol/validator/logs"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"golang.org/x/xerrors"
+)
+
+var logger = logs.Logger("grid syncer")
+
+// chain sync operations provided by the dumper
+type Dumper interface {
+	// dump all chain data into db
+	DumpGRID() error
+	// follow chain events until ctx is canceled or the connection drops
+	SubscribeGRID(ctx context.Context) error
+}
+
+// dumpers that report the last block their subscription processed
+type BlockReporter interface {
+	SyncedBlock() (uint64, error)
+}
+
+// keeps the synced block across restarts
+type BlockStore interface {
+	// logs.ErrNotExist before the first dump
+	GetSyncedBlock() (uint64, error)
+	SetSyncedBlock(block uint64) error
+}
+
+type Config struct {
+	// backoff before restarting a dropped subscription, doubled on each failure
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// interval to poll chain head
+	PollInterval time.Duration
+	// sync lag in blocks above which the db is considered stale
+	MaxLag uint64
+	// persists the synced block, optional
+	Blocks BlockStore
+}
+
+func DefaultConfig() Config {
+	return Config{
+		MinBackoff:   time.Second,
+		MaxBackoff:   time.Minute,
+		PollInterval: 10 * time.Second,
+		MaxLag:       20,
+	}
+}
+
+// sync state of the local db, the synced block is the one the dumper is known to have processed
+type Status struct {
+	Running     bool      `json:"running"`
+	Dumping     bool      `json:"dumping"`
+	SyncedBlock uint64    `json:"synced_block"`
+	HeadBlock   uint64    `json:"head_block"`
+	Lag         uint64    `json:"lag"`
+	Restarts    int       `json:"restarts"`
+	LastError   string    `json:"last_error,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// keep the dumper subscription alive and track how far the db is behind the chain
+type Supervisor struct {
+	dumper Dumper
+	client *ethclient.Client
+	cfg    Config
+
+	lk     sync.RWMutex
+	status Status
+	// cancels the running subscription, set while Run is subscribed
+	cancelSub context.CancelFunc
+	// why chain data is dumped before subscribing again, empty if it is not
+	redump string
+	// counts the subscriptions started, a poll that sees the same one running twice
+	// knows the events of the blocks in between were processed
+	subscription uint64
+
+	// orders the writes of the synced block
+	persistLk sync.Mutex
+}
+
+func NewSupervisor(dumper Dumper, endpoint string, cfg Config) (*Supervisor, error) {
+	client, err := ethclient.Dial(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Supervisor{
+		dumper: dumper,
+		client: client,
+		cfg:    cfg,
+	}
+
+	if cfg.Blocks != nil {
+		block, err := cfg.Blocks.GetSyncedBlock()
+		if err == nil {
+			s.status.SyncedBlock = block
+		} else if !errors.Is(err, logs.ErrNotExist) {
+			return nil, xerrors.Errorf("read synced block: %w", err)
+		}
+	}
+
+	return s, nil
+}
+
+// dump chain data, the db is synced at least to the head seen before dumping
+func (s *Supervisor) Dump(ctx context.Context) error {
+	head, err := s.client.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.setDumping(true)
+	err = s.dumper.DumpGRID()
+	s.setDumping(false)
+	if err != nil {
+		return err
+	}
+
+	s.lk.Lock()
+	changed := s.setSynced(head)
+	s.setHead(head)
+	s.lk.Unlock()
+	s.persistSynced(changed)
+
+	logger.Infof("db dumped to block %d", head)
+
+	return nil
+}
+
+// run the subscription until ctx is canceled, restart it with backoff when it drops
+func (s *Supervisor) Run(ctx context.Context) error {
+	go s.pollHead(ctx)
+
+	backoff := s.cfg.MinBackoff
+	for {
+		subCtx, cancel := context.WithCancel(ctx)
+		s.lk.Lock()
+		s.cancelSub = cancel
+		s.status.Running = true
+		s.subscription++
+		s.lk.Unlock()
+
+		start := time.Now()
+		err := s.dumper.SubscribeGRID(subCtx)
+		cancel()
+
+		s.lk.Lock()
+		s.cancelSub = nil
+		s.status.Running = false
+		redump := s.redump
+		s.redump = ""
+		s.lk.Unlock()
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		// dump chain data again before subscribing
+		if redump != "" {
+			logger.Debugf("dump chain data to %s", redump)
+			err = s.Dump(ctx)
+			if err == nil {
+				continue
+			}
+		}
+
+		if err == nil {
+			err = xerrors.New("subscription closed")
+		}
+
+		// a subscription that lived long enough resets backoff
+		if time.Since(start) > s.cfg.MaxBackoff {
+			backoff = s.cfg.MinBackoff
+		}
+
+		for {
+			s.setError(err)
+			logger.Warnf("chain subscription stopped: %s, restart in %s", err, backoff)
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(backoff):
+			}
+
+			backoff *= 2
+			if backoff > s.cfg.MaxBackoff {
+				backoff = s.cfg.MaxBackoff
+			}
+
+			// fill the gap missed while disconnected
+			err = s.Dump(ctx)
+			if err == nil {
+				break
+			}
+		}
+	}
+}
+
+// restart the subscription after dumping all chain data again, returns before the dump is done
+func (s *Supervisor) Resync() error {
+	logger.Info("resync db with chain")
+	return s.restart("resync the db with chain")
+}
+
+func (s *Supervisor) restart(redump string) error {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	if s.cancelSub == nil {
+		return xerrors.New("chain subscription is not running")
+	}
+
+	s.redump = redump
+	s.cancelSub()
+	return nil
+}
+
+// check the chain rpc is reachable
+func (s *Supervisor) Ping(ctx context.Context) error {
+	_, err := s.client.BlockNumber(ctx)
+	return err
+}
+
+func (s *Supervisor) Status() Status {
+	s.lk.RLock()
+	defer s.lk.RUnlock()
+
+	return s.status
+}
+
+// whether the db is too far behind the chain to be trusted
+func (s *Supervisor) Stale() bool {
+	status := s.Status()
+	return (!status.Running && !status.Dumping) || status.Lag > s.cfg.MaxLag
+}
+
+// poll chain head and the block the dumper processed.
+// a dumper that can not report it is synced to the head seen at the previous poll if the same
+// subscription ran since, it processed the events of those blocks as they arrived.
+// the synced block stays behind while the subscription is down, until the next dump
+func (s *Supervisor) pollHead(ctx context.Context) {
+	reporter, reports := s.dumper.(BlockReporter)
+
+	// head and subscription seen at the previous poll
+	var lastHead, lastSubscription uint64
+
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		head, err := s.client.BlockNumber(ctx)
+		if err != nil {
+			logger.Debugf("get chain head: %s", err)
+			continue
+		}
+
+		var synced uint64
+		measured := false
+		if reports {
+			synced, err = reporter.SyncedBlock()
+			if err == nil {
+				measured = true
+			} else {
+				logger.Debugf("get synced block: %s", err)
+			}
+		}
+
+		s.lk.Lock()
+		sameSubscription := s.status.Running && s.subscription == lastSubscription
+		if !reports && sameSubscription && lastHead > s.status.SyncedBlock {
+			synced, measured = lastHead, true
+		}
+		changed := measured && s.setSynced(synced)
+		s.setHead(head)
+		lastHead, lastSubscription = head, s.subscription
+		if !s.status.Running {
+			// a subscription must be seen running at two polls
+			lastSubscription = 0
+		}
+		s.lk.Unlock()
+		s.persistSynced(changed)
+	}
+}
+
+// caller holds lk, returns whether the block changed
+func (s *Supervisor) setSynced(block uint64) bool {
+	if block == s.status.SyncedBlock {
+		return false
+	}
+	s.status.SyncedBlock = block
+	return true
+}
+
+// write the synced block after it changed, without holding lk.
+// the latest one is written, so concurrent writes do not go back
+func (s *Supervisor) persistSynced(changed bool) {
+	if !changed || s.cfg.Blocks == nil {
+		return
+	}
+
+	s.persistLk.Lock()
+	defer s.persistLk.Unlock()
+
+	block := s.Status().SyncedBlock
+	err := s.cfg.Blocks.SetSyncedBlock(block)
+	if err != nil {
+		logger.Warnf("persist synced block %d: %s", block, err)
+	}
+}
+
+func (s *Supervisor) setDumping(dumping bool) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	s.status.Dumping = dumping
+}
+
+// caller holds lk
+func (s *Supervisor) setHead(head uint64) {
+	s.status.HeadBlock = head
+	s.status.Lag = 0
+	if head > s.status.SyncedBlock {
+		s.status.Lag = head - s.status.SyncedBlock
+	}
+	s.status.UpdatedAt = time.Now()
+
+	metrics.SyncedBlock.Set(float64(s.status.SyncedBlock))
+	metrics.HeadBlock.Set(float64(head))
+	metrics.SyncLag.Set(float64(s.status.Lag))
+}
+
+func (s *Supervisor) setError(err error) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	s.status.Restarts++
+	metrics.SyncRestarts.Inc()
+	s.status.LastError = err.Error()
+}
@@ -0,0 +1,165 @@
+package syncer
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gridprotocol/validator/core/store"
+	"github.com/gridprotocol/validator/core/types"
+	"github.com/gridprotocol/validator/logs"
+
+	"golang.org/x/xerrors"
+)
+
+// store of the records synced from chain
+type StateReader interface {
+	ListActiveOrders() ([]store.Order, error)
+	GetProfit(provider string) (store.Profit, error)
+}
+
+// active orders by node and the profits of their providers
+type State struct {
+	Orders  map[types.NodeID]store.Order
+	Profits map[string]store.Profit
+}
+
+func ReadState(r StateReader) (State, error) {
+	orders, err := r.ListActiveOrders()
+	if err != nil {
+		return State{}, err
+	}
+
+	state := State{
+		Orders:  make(map[types.NodeID]store.Order),
+		Profits: make(map[string]store.Profit),
+	}
+	for _, order := range orders {
+		state.Orders[types.NodeID{Provider: order.Provider, ID: order.ID}] = order
+		if _, ok := state.Profits[order.Provider]; ok {
+			continue
+		}
+
+		profit, err := r.GetProfit(order.Provider)
+		if errors.Is(err, logs.ErrNotExist) {
+			// reported as a missing profit
+			continue
+		}
+		if err != nil {
+			return State{}, xerrors.Errorf("profit of %s: %w", order.Provider, err)
+		}
+		state.Profits[order.Provider] = profit
+	}
+
+	return state, nil
+}
+
+// kind of a discrepancy
+const (
+	// on chain but not in the local db
+	DiffMissing = "missing"
+	// in the local db but not on chain
+	DiffStale = "stale"
+	// in both with different values
+	DiffMismatch = "mismatch"
+)
+
+// a record of the local db that differs from chain
+type Discrepancy struct {
+	Kind string
+	// e.g.(order 0xab/12, profit 0xab)
+	Record string
+	// the field of a mismatch
+	Field string
+	Local string
+	Chain string
+}
+
+// how the local state differs from the chain state, orders first.
+// amounts of profits are settled by the validator, only the fields set on chain are compared
+func Compare(local, chain State) []Discrepancy {
+	var res []Discrepancy
+
+	nodes := make(map[types.NodeID]struct{})
+	for node := range local.Orders {
+		nodes[node] = struct{}{}
+	}
+	for node := range chain.Orders {
+		nodes[node] = struct{}{}
+	}
+	for _, node := range sortedNodes(nodes) {
+		record := "order " + node.Provider + "/" + strconv.FormatUint(node.ID, 10)
+		l, inLocal := local.Orders[node]
+		c, onChain := chain.Orders[node]
+		switch {
+		case !inLocal:
+			res = append(res, Discrepancy{Kind: DiffMissing, Record: record})
+		case !onChain:
+			res = append(res, Discrepancy{Kind: DiffStale, Record: record})
+		default:
+			res = appendMismatch(res, record, "activate_time", formatTime(l.ActivateTime), formatTime(c.ActivateTime))
+			res = appendMismatch(res, record, "probation", strconv.FormatInt(l.Probation, 10), strconv.FormatInt(c.Probation, 10))
+			res = appendMismatch(res, record, "duration", strconv.FormatInt(l.Duration, 10), strconv.FormatInt(c.Duration, 10))
+		}
+	}
+
+	providers := make(map[string]struct{})
+	for _, order := range local.Orders {
+		providers[order.Provider] = struct{}{}
+	}
+	for _, order := range chain.Orders {
+		providers[order.Provider] = struct{}{}
+	}
+	for _, provider := range sortedKeys(providers) {
+		record := "profit " + provider
+		l, inLocal := local.Profits[provider]
+		c, onChain := chain.Profits[provider]
+		switch {
+		case !inLocal && !onChain:
+		case !inLocal:
+			res = append(res, Discrepancy{Kind: DiffMissing, Record: record})
+		case !onChain:
+			res = append(res, Discrepancy{Kind: DiffStale, Record: record})
+		default:
+			res = appendMismatch(res, record, "nonce", strconv.FormatUint(l.Nonce, 10), strconv.FormatUint(c.Nonce, 10))
+			res = appendMismatch(res, record, "end_time", formatTime(l.EndTime), formatTime(c.EndTime))
+		}
+	}
+
+	return res
+}
+
+func appendMismatch(res []Discrepancy, record, field, local, chain string) []Discrepancy {
+	if local == chain {
+		return res
+	}
+	return append(res, Discrepancy{Kind: DiffMismatch, Record: record, Field: field, Local: local, Chain: chain})
+}
+
+// times are compared at second precision in utc, the dbs store them differently
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// nodes by provider, then id
+func sortedNodes(m map[types.NodeID]struct{}) []types.NodeID {
+	nodes := make([]types.NodeID, 0, len(m))
+	for node := range m {
+		nodes = append(nodes, node)
+	}
+	slices.SortFunc(nodes, func(a, b types.NodeID) int {
+		return cmp.Or(cmp.Compare(a.Provider, b.Provider), cmp.Compare(a.ID, b.ID))
+	})
+	return nodes
+}
+
+func sortedKeys[K cmp.Ordered](m map[K]struct{}) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -2,6 +2,7 @@ package types
 
 import (
 	"encoding/binary"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 )
@@ -35,3 +36,161 @@ type Result struct {
 	NodeID
 	Success bool
 }
+
+// response of GET /rnd
+type RNDResponse struct {
+	RND string `json:"rnd"`
+}
+
+// type of a cycle event
+const (
+	// a new phase of the cycle begins
+	EventPhase = "phase"
+	// the random value of the prove period
+	EventRND = "rnd"
+	// proof results of all nodes of a settled cycle
+	EventVerdicts = "verdicts"
+	// summary of a settled cycle
+	EventSettlement = "settlement"
+	// the subscriber fell behind and missed events, its stream ends after this
+	EventLagged = "lagged"
+)
+
+// cycle event pushed by GET /events, fields are set according to type
+type Event struct {
+	Type       string             `json:"type"`
+	Cycle      int64              `json:"cycle"`
+	Time       time.Time          `json:"time"`
+	Phase      string             `json:"phase,omitempty"`
+	RND        string             `json:"rnd,omitempty"`
+	Verdicts   []NodeVerdict      `json:"verdicts,omitempty"`
+	Settlement *SettlementSummary `json:"settlement,omitempty"`
+}
+
+type NodeVerdict struct {
+	NodeID
+	Passed bool `json:"passed"`
+}
+
+type SettlementSummary struct {
+	Challenged int `json:"challenged"`
+	Passed     int `json:"passed"`
+	Failed     int `json:"failed"`
+	// seconds spent settling
+	Duration float64 `json:"duration"`
+}
+
+// profit record of a provider, amounts are decimal strings
+type ProfitInfo struct {
+	Address string `json:"address"`
+	Balance string `json:"balance"`
+	// pending profit not yet paid into balance
+	Profit   string    `json:"profit"`
+	Penalty  string    `json:"penalty"`
+	Nonce    uint64    `json:"nonce"`
+	LastTime time.Time `json:"last_time"`
+	EndTime  time.Time `json:"end_time"`
+}
+
+// active order of a provider
+type OrderInfo struct {
+	ID           uint64    `json:"id"`
+	Provider     string    `json:"provider"`
+	ActivateTime time.Time `json:"activate_time"`
+	Probation    int64     `json:"probation"`
+	Duration     int64     `json:"duration"`
+}
+
+// challenge status of a provider node
+type NodeStatus struct {
+	NodeID
+	// has an active order, so it is challenged each cycle
+	Active    bool  `json:"active"`
+	Passed    int   `json:"passed"`
+	Failed    int   `json:"failed"`
+	LastCycle int64 `json:"last_cycle"`
+	// result of the last challenged cycle
+	LastPassed bool `json:"last_passed"`
+}
+
+// validator wide statistics, results are counted over the last Cycles cycles
+type Stats struct {
+	// last settled cycle
+	Cycle        int64 `json:"cycle"`
+	Cycles       int64 `json:"cycles"`
+	ActiveOrders int   `json:"active_orders"`
+	Providers    int   `json:"providers"`
+	// distinct nodes challenged
+	ChallengedNodes int64   `json:"challenged_nodes"`
+	Challenges      int64   `json:"challenges"`
+	Passed          int64   `json:"passed"`
+	Failed          int64   `json:"failed"`
+	PassRate        float64 `json:"pass_rate"`
+	// decimal amounts over all cycles
+	TotalReward  string             `json:"total_reward"`
+	TotalPenalty string             `json:"total_penalty"`
+	TopFailing   []ProviderFailures `json:"top_failing"`
+}
+
+type ProviderFailures struct {
+	Provider string `json:"provider"`
+	Failed   int64  `json:"failed"`
+}
+
+// verdict of a proof in a batch
+type ProofVerdict struct {
+	NodeID
+	Accepted bool   `json:"accepted"`
+	Code     string `json:"code,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// trailer set when an export fails after its response started
+const ExportErrorTrailer = "X-Export-Error"
+
+// records to export, the cycle range is narrowed by the dates
+type ExportQuery struct {
+	// cycles, results or ledger
+	Dataset string `form:"dataset"`
+	// csv, jsonl or parquet
+	Format    string `form:"format"`
+	FromCycle int64  `form:"from_cycle"`
+	ToCycle   int64  `form:"to_cycle"`
+	// utc days, the cycles started from the beginning of From to the end of To
+	From     time.Time `form:"from" time_format:"2006-01-02" time_utc:"1"`
+	To       time.Time `form:"to" time_format:"2006-01-02" time_utc:"1"`
+	Provider string    `form:"provider"`
+}
+
+// admin api
+
+// level of the named logger, or the global level if name is empty
+type LogLevel struct {
+	Name  string `json:"name,omitempty"`
+	Level string `json:"level"`
+}
+
+type LogLevels struct {
+	Level string `json:"level"`
+	// effective level of each named logger
+	Names map[string]string `json:"names"`
+}
+
+// whether the validator loop challenges nodes
+type ChallengeState struct {
+	Paused bool `json:"paused"`
+}
+
+// results collected in the prove period of a cycle
+type CycleResults struct {
+	Cycle int64 `json:"cycle"`
+	// proofs are accepted
+	Open  bool          `json:"open"`
+	Nodes []NodeVerdict `json:"nodes"`
+}
+
+// pow difficulty of a node, overriding the default
+type NodeDifficulty struct {
+	NodeID
+	Difficulty int `json:"difficulty"`
+}
@@ -0,0 +1,169 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/gridprotocol/validator/core/metrics"
+	"github.com/gridprotocol/validator/core/store"
+	"github.com/gridprotocol/validator/core/tracing"
+	"github.com/gridprotocol/validator/core/types"
+	"github.com/gridprotocol/validator/logs"
+)
+
+// policy of cycles skipped while challenging is paused
+const pausedPolicy = "paused"
+
+var ErrCycleInProgress = logs.Unavailable{Message: "a cycle is in progress, trigger again after it is settled"}
+
+// stop challenging nodes, the following cycles are recorded as skipped until Resume
+func (v *GRIDValidator) Pause() {
+	v.paused.Store(true)
+	logger.Info("challenging paused")
+}
+
+func (v *GRIDValidator) Resume() {
+	v.paused.Store(false)
+	logger.Info("challenging resumed")
+}
+
+func (v *GRIDValidator) Paused() bool {
+	return v.paused.Load()
+}
+
+// start the next cycle now instead of waiting for its time, it is run even if challenging is paused.
+// the regular cycle it replaces is not run again
+func (v *GRIDValidator) TriggerCycle() error {
+	select {
+	case v.trigger <- struct{}{}:
+		logger.Info("cycle triggered manually")
+		return nil
+	default:
+		return ErrCycleInProgress
+	}
+}
+
+// wait for d or a manual trigger, skip is the part of the cycle before the waited phase.
+// returns whether the cycle was triggered, ok is false if ctx is done
+func (v *GRIDValidator) waitOrTrigger(ctx context.Context, d, skip time.Duration) (triggered bool, ok bool) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false, false
+	case <-timer.C:
+		return false, true
+	case <-v.trigger:
+		// the cycle starts now, so the waited phase begins at once
+		v.last = time.Now().Add(-skip).Unix()
+		return true, true
+	}
+}
+
+// record a cycle passed while challenging is paused, so it is not recovered later
+func (v *GRIDValidator) skipCycle(ctx context.Context) error {
+	cycle := store.Cycle{
+		ID:        v.cycle,
+		StartTime: time.Unix(v.last, 0),
+		Status:    store.CycleSkipped,
+		Policy:    pausedPolicy,
+		SettledAt: time.Now(),
+	}
+	err := v.db.SettleCycle(ctx, &cycle, nil, func(store.ProfitReader) ([]store.Ledger, error) {
+		return nil, nil
+	})
+	if errors.Is(err, logs.ErrAlreadyExist) {
+		logger.Infof("cycle %d is recorded by another validator", cycle.ID)
+	} else if err != nil {
+		return err
+	}
+
+	v.lastCycle = cycle.ID
+	metrics.CyclesTotal.WithLabelValues(store.CycleSkipped).Inc()
+	return nil
+}
+
+// results of the cycle in its prove period, or of the last collected cycle
+func (v *GRIDValidator) CycleResults() types.CycleResults {
+	v.resultLk.Lock()
+	defer v.resultLk.Unlock()
+
+	res := types.CycleResults{
+		Cycle: v.resultsCycle,
+		Open:  v.results != nil,
+		Nodes: []types.NodeVerdict{},
+	}
+
+	results := v.results
+	if results == nil {
+		results = v.lastResults
+	}
+	for nodeID, passed := range results {
+		res.Nodes = append(res.Nodes, types.NodeVerdict{NodeID: nodeID, Passed: passed})
+	}
+	sort.Slice(res.Nodes, func(i, j int) bool {
+		a, b := res.Nodes[i], res.Nodes[j]
+		if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		}
+		return a.ID < b.ID
+	})
+
+	return res
+}
+
+// override the pow difficulty of a node, 0 restores the default
+func (v *GRIDValidator) SetDifficulty(nodeID types.NodeID, difficulty int) error {
+	if difficulty < 0 || difficulty >= 256 {
+		return logs.BadRequest{Message: "difficulty must be in [0, 256)"}
+	}
+
+	v.difficultyLk.Lock()
+	defer v.difficultyLk.Unlock()
+
+	if difficulty == 0 {
+		delete(v.difficulty, nodeID)
+	} else {
+		v.difficulty[nodeID] = difficulty
+	}
+
+	logger.Infof("difficulty of node %s/%d set to %d", nodeID.Provider, nodeID.ID, difficulty)
+	return nil
+}
+
+// overridden difficulties
+func (v *GRIDValidator) Difficulties() []types.NodeDifficulty {
+	v.difficultyLk.RLock()
+	defer v.difficultyLk.RUnlock()
+
+	res := []types.NodeDifficulty{}
+	for nodeID, difficulty := range v.difficulty {
+		res = append(res, types.NodeDifficulty{NodeID: nodeID, Difficulty: difficulty})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Provider != res[j].Provider {
+			return res[i].Provider < res[j].Provider
+		}
+		return res[i].ID < res[j].ID
+	})
+
+	return res
+}
+
+// difficulty of the node, overridden or the default
+func (v *GRIDValidator) difficultyOf(ctx context.Context, nodeID types.NodeID) (_ int, err error) {
+	_, span := tracing.Start(ctx, "difficulty lookup")
+	defer tracing.End(span, &err)
+
+	v.difficultyLk.RLock()
+	difficulty, ok := v.difficulty[nodeID]
+	v.difficultyLk.RUnlock()
+	if ok {
+		return difficulty, nil
+	}
+
+	return getDiffcultByProviderId(nodeID)
+}
@@ -0,0 +1,138 @@
+package validator
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gridprotocol/validator/core/types"
+)
+
+// buffered events per subscriber, a slow subscriber is dropped beyond it
+const subscriberBuffer = 256
+
+// fan out cycle events to stream subscribers
+type eventBroker struct {
+	lk     sync.Mutex
+	subs   map[chan types.Event]struct{}
+	closed bool
+}
+
+func newEventBroker() *eventBroker {
+	return &eventBroker{
+		subs: make(map[chan types.Event]struct{}),
+	}
+}
+
+// the returned func unsubscribes, the channel is closed when the broker closes
+func (b *eventBroker) Subscribe() (<-chan types.Event, func()) {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	// one more slot for the lagged event
+	ch := make(chan types.Event, subscriberBuffer+1)
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+
+	b.subs[ch] = struct{}{}
+	return ch, func() {
+		b.lk.Lock()
+		defer b.lk.Unlock()
+
+		if _, ok := b.subs[ch]; ok {
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// never blocks the validator loop. a subscriber with a full buffer gets a lagged event
+// and is unsubscribed, so it never misses events silently
+func (b *eventBroker) Publish(event types.Event) {
+	event.Time = time.Now()
+
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	for ch := range b.subs {
+		if len(ch) < subscriberBuffer {
+			ch <- event
+			continue
+		}
+
+		logger.Debugf("drop slow subscriber at %s event", event.Type)
+		ch <- types.Event{
+			Type:  types.EventLagged,
+			Cycle: event.Cycle,
+			Time:  event.Time,
+		}
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
+
+// end all streams
+func (b *eventBroker) Close() {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	b.closed = true
+	for ch := range b.subs {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
+
+// subscribe cycle events
+func (v *GRIDValidator) SubscribeEvents() (<-chan types.Event, func()) {
+	return v.events.Subscribe()
+}
+
+func (v *GRIDValidator) publishPhase(cycle int64, phase string) {
+	v.events.Publish(types.Event{
+		Type:  types.EventPhase,
+		Cycle: cycle,
+		Phase: phase,
+	})
+}
+
+// publish the verdicts of the nodes in one event, then the summary of a settled cycle
+func (v *GRIDValidator) publishSettlement(cycle int64, res map[types.NodeID]bool, duration time.Duration) {
+	summary := types.SettlementSummary{
+		Challenged: len(res),
+		Duration:   duration.Seconds(),
+	}
+
+	verdicts := make([]types.NodeVerdict, 0, len(res))
+	for nodeID, passed := range res {
+		verdicts = append(verdicts, types.NodeVerdict{
+			NodeID: nodeID,
+			Passed: passed,
+		})
+
+		if passed {
+			summary.Passed++
+		} else {
+			summary.Failed++
+		}
+	}
+	sort.Slice(verdicts, func(i, j int) bool {
+		if verdicts[i].Provider != verdicts[j].Provider {
+			return verdicts[i].Provider < verdicts[j].Provider
+		}
+		return verdicts[i].ID < verdicts[j].ID
+	})
+
+	v.events.Publish(types.Event{
+		Type:     types.EventVerdicts,
+		Cycle:    cycle,
+		Verdicts: verdicts,
+	})
+	v.events.Publish(types.Event{
+		Type:       types.EventSettlement,
+		Cycle:      cycle,
+		Settlement: &summary,
+	})
+}
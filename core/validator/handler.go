@@ -1,45 +1,146 @@
 package validator
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"io"
 	"math/big"
 	"net/http"
+	"sync"
+	"time"
 
-	"github.com/gridprotocol/dumper/database"
+	"github.com/gridprotocol/validator/core/export"
+	"github.com/gridprotocol/validator/core/metrics"
+	"github.com/gridprotocol/validator/core/middleware"
+	"github.com/gridprotocol/validator/core/tracing"
 	"github.com/gridprotocol/validator/core/types"
+	"github.com/gridprotocol/validator/logs"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
 )
 
-// register all route
-func (v *GRIDValidator) LoadValidatorModule(rg *gin.RouterGroup) {
+// register all route, privileged handlers guard routes that sign for the validator
+func (v *GRIDValidator) LoadValidatorModule(rg *gin.RouterGroup, privileged ...gin.HandlerFunc) {
 	rg.GET("/rnd", v.GetRNDHandler)
-	rg.GET("/withdraw/signature", v.GetWithdrawSignatureHandler)
+	rg.Group("", privileged...).GET("/withdraw/signature", v.GetWithdrawSignatureHandler)
 	rg.POST("/proof", v.SubmitProofHandler)
+	rg.POST("/proofs", v.SubmitProofsHandler)
 
 	// get order count of a provider
 	rg.GET("/provider/:address/count", v.GetOrderCountHandler())
 
+	// provider dashboard
+	rg.GET("/provider/:address/profit", v.GetProfitHandler)
+	rg.GET("/provider/:address/orders", v.GetOrdersHandler)
+	rg.GET("/provider/:address/nodes", v.GetNodesHandler)
+
+	// validator wide statistics
+	rg.GET("/stats", v.GetStatsHandler)
+
+	// stream of cycle events
+	rg.GET("/events", v.GetEventsHandler)
+
+	// settlement and proof history
+	rg.Group("", privileged...).GET("/export", v.GetExportHandler)
+
+	// api document
+	rg.GET("/openapi.json", v.GetOpenAPIHandler)
+
 	fmt.Println("load light node moudle success!")
 }
 
 func (v *GRIDValidator) GetRNDHandler(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"rnd": hex.EncodeToString(RND[:]),
+	c.JSON(http.StatusOK, types.RNDResponse{
+		RND: hex.EncodeToString(RND[:]),
+	})
+}
+
+// interval of keepalive comments on idle event streams
+const eventsKeepalive = 15 * time.Second
+
+// push cycle events as server-sent events until the client leaves or the validator stops
+func (v *GRIDValidator) GetEventsHandler(c *gin.Context) {
+	events, cancel := v.SubscribeEvents()
+	defer cancel()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Type, event)
+			return true
+		case <-time.After(eventsKeepalive):
+			_, err := io.WriteString(w, ": keepalive\n\n")
+			return err == nil
+		}
 	})
 }
 
+// sent to the client when an export fails, the cause is only logged
+const exportFailed = "export failed, retry the export"
+
+// stream cycles, proof results or ledger entries of a cycle range as csv, json lines or parquet
+func (v *GRIDValidator) GetExportHandler(c *gin.Context) {
+	var q types.ExportQuery
+	err := c.ShouldBindQuery(&q)
+	if err != nil {
+		middleware.AbortWithError(c, logs.BadRequest{Message: err.Error()})
+		return
+	}
+	if q.Format == "" {
+		q.Format = export.FormatCSV
+	}
+
+	err = export.Check(q)
+	if err != nil {
+		middleware.AbortWithError(c, err)
+		return
+	}
+
+	filter := export.Filter(q)
+	c.Header("Content-Type", export.ContentType(q.Format))
+	c.Header("Content-Disposition", `attachment; filename="`+export.FileName(q, filter)+`"`)
+	// the status is sent before the records, a failure is reported in the trailer
+	c.Header("Trailer", types.ExportErrorTrailer)
+	c.Status(http.StatusOK)
+
+	err = export.Write(c.Request.Context(), v.db, c.Writer, q, filter)
+	if err != nil {
+		middleware.Logger(c).Errorw("export failed", "dataset", q.Dataset, "error", err)
+		if !c.Writer.Written() {
+			// nothing is sent yet, the json error replaces the export
+			c.Writer.Header().Del("Content-Type")
+			c.Writer.Header().Del("Content-Disposition")
+			c.Writer.Header().Del("Trailer")
+			middleware.AbortWithError(c, logs.DataBaseError{Message: exportFailed})
+			return
+		}
+		c.Writer.Header().Set(types.ExportErrorTrailer, exportFailed)
+	}
+}
+
 // get order count of a provider
 func (v *GRIDValidator) GetOrderCountHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// provider address
-		address := c.Param("address")
-		cnt, err := database.GetOrderCount(address)
+		address, ok := providerParam(c)
+		if !ok {
+			return
+		}
+
+		cnt, err := v.db.GetOrderCount(address)
 		if err != nil {
-			logger.Error(err.Error())
-			c.AbortWithStatusJSON(500, err.Error())
+			abortQueryError(c, err, "orders of "+address)
 			return
 		}
 
@@ -49,19 +150,161 @@ func (v *GRIDValidator) GetOrderCountHandler() gin.HandlerFunc {
 
 // proof handler
 func (v *GRIDValidator) SubmitProofHandler(c *gin.Context) {
+	log := middleware.Logger(c)
+
+	// never wait for a slot, the prover retries later
+	release, err := v.acquireIntake(1)
+	if err != nil {
+		log.Warn(err.Error())
+		metrics.ProofsTotal.WithLabelValues(metrics.ReasonSaturated).Inc()
+		c.Header("Retry-After", "1")
+		middleware.AbortWithError(c, err)
+		return
+	}
+	defer release()
+
 	var proof types.Proof
-	err := c.BindJSON(&proof)
+	err = c.ShouldBindJSON(&proof)
+	if err != nil {
+		log.Error(err)
+		metrics.ProofsTotal.WithLabelValues(metrics.ReasonBadRequest).Inc()
+		middleware.AbortWithError(c, logs.BadRequest{Message: err.Error()})
+		return
+	}
+	log = middleware.WithProvider(c, proof.Provider)
+
+	err = middleware.CheckProvider(c, proof.Provider, 1)
+	if err != nil {
+		log.Warn(err.Error())
+		metrics.ProofsTotal.WithLabelValues(metrics.ReasonRateLimited).Inc()
+		middleware.AbortWithError(c, err)
+		return
+	}
+
+	reason, err := v.verifyProof(c.Request.Context(), log, proof)
+	if err != nil {
+		if reason == metrics.ReasonBadPOW {
+			middleware.ReportInvalidProof(c)
+		}
+		middleware.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, "Verify Proof Success")
+}
+
+// max proofs in one batch
+const maxBatchProofs = 1024
+
+// max proofs of a batch verified at the same time
+const batchWorkers = 8
+
+// verify a batch of proofs in parallel, each proof gets its own verdict
+func (v *GRIDValidator) SubmitProofsHandler(c *gin.Context) {
+	log := middleware.Logger(c)
+
+	var proofs []types.Proof
+	err := c.ShouldBindJSON(&proofs)
 	if err != nil {
-		logger.Error(err)
-		c.AbortWithStatusJSON(500, err.Error())
+		log.Error(err)
+		metrics.ProofsTotal.WithLabelValues(metrics.ReasonBadRequest).Inc()
+		middleware.AbortWithError(c, logs.BadRequest{Message: err.Error()})
+		return
+	}
+
+	if len(proofs) > maxBatchProofs {
+		log.Errorf("too many proofs in batch: %d", len(proofs))
+		middleware.AbortWithError(c, logs.BadRequest{Message: fmt.Sprintf("at most %d proofs in one batch", maxBatchProofs)})
 		return
 	}
 
+	// a batch takes a slot per proof
+	release, err := v.acquireIntake(len(proofs))
+	if err != nil {
+		log.Warn(err.Error())
+		metrics.ProofsTotal.WithLabelValues(metrics.ReasonSaturated).Add(float64(len(proofs)))
+		c.Header("Retry-After", "1")
+		middleware.AbortWithError(c, err)
+		return
+	}
+	defer release()
+
+	// one provider token per proof, a provider over its limit has all its proofs of the batch rejected
+	counts := make(map[string]int)
+	for _, proof := range proofs {
+		counts[proof.Provider]++
+	}
+	limited := make(map[string]error)
+	for provider, n := range counts {
+		limited[provider] = middleware.CheckProvider(c, provider, n)
+	}
+
+	verdicts := make([]types.ProofVerdict, len(proofs))
+	workers := make(chan struct{}, batchWorkers)
+	var wg sync.WaitGroup
+	for i, proof := range proofs {
+		wg.Add(1)
+		workers <- struct{}{}
+		go func() {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+
+			verdicts[i].NodeID = proof.NodeID
+			err := limited[proof.Provider]
+			if err != nil {
+				metrics.ProofsTotal.WithLabelValues(metrics.ReasonRateLimited).Inc()
+				apiErr := logs.ToAPIErrorCode(err)
+				verdicts[i].Code = apiErr.Code
+				verdicts[i].Error = apiErr.Description
+				return
+			}
+
+			reason, err := v.verifyProof(c.Request.Context(), log.With("provider", proof.Provider), proof)
+			if err != nil {
+				if reason == metrics.ReasonBadPOW {
+					middleware.ReportInvalidProof(c)
+				}
+				apiErr := logs.ToAPIErrorCode(err)
+				verdicts[i].Code = apiErr.Code
+				verdicts[i].Error = apiErr.Description
+				return
+			}
+			verdicts[i].Accepted = true
+		}()
+	}
+	wg.Wait()
+
+	c.JSON(http.StatusOK, verdicts)
+}
+
+// verify pow of the proof and record it into current cycle,
+// reason is the outcome label for metrics
+func (v *GRIDValidator) verifyProof(ctx context.Context, log *zap.SugaredLogger, proof types.Proof) (reason string, err error) {
+	ctx, span := tracing.Start(ctx, "verify proof",
+		attribute.String("provider", proof.Provider),
+		attribute.Int64("node", int64(proof.ID)),
+	)
+	defer func() {
+		span.SetAttributes(attribute.String("reason", reason))
+		tracing.End(span, &err)
+	}()
+
+	reason, err = v.checkProof(ctx, log, proof)
+	metrics.ProofsTotal.WithLabelValues(reason).Inc()
+	if err != nil {
+		// floods of bad proofs would drown the other logs
+		logs.Sampled(log).Errorw("proof rejected: "+reason, "node", proof.ID, "error", err)
+	}
+
+	return reason, err
+}
+
+func (v *GRIDValidator) checkProof(ctx context.Context, log *zap.SugaredLogger, proof types.Proof) (string, error) {
 	// check proof time
 	if !v.IsProveTime() {
-		logger.Error("Failure to submit proof within the proof time")
-		c.AbortWithStatusJSON(400, "Failure to submit proof within the proof time")
-		return
+		return metrics.ReasonOutsideWindow, ErrOutsideWindow
 	}
 
 	// make result with proof and rnd
@@ -71,27 +314,25 @@ func (v *GRIDValidator) SubmitProofHandler(c *gin.Context) {
 	result := hash.Sum(nil)
 
 	// get difficult
-	diffcult, err := getDiffcultByProviderId(proof.NodeID)
+	diffcult, err := v.difficultyOf(ctx, proof.NodeID)
 	if err != nil {
-		logger.Error(err)
-		c.AbortWithStatusJSON(500, err.Error())
-		return
+		log.Errorw("difficulty of the node", "node", proof.ID, "error", err)
+		return metrics.ReasonDifficulty, logs.DataBaseError{Message: "difficulty of the node is unavailable"}
 	}
 
 	// check pow with result and dificult
 	if !checkPOWResult(result, diffcult) {
-		logger.Error("Verify Proof Failed:", hex.EncodeToString(result))
-		c.AbortWithStatusJSON(400, "Verify Proof Failed")
-		return
+		logs.Sampled(log).Debugw("verify proof failed", "result", hex.EncodeToString(result))
+		return metrics.ReasonBadPOW, ErrBadPOW
 	}
 
-	// send succeeded proof to chan
-	resultChan <- types.Result{
-		NodeID:  proof.NodeID,
-		Success: true,
+	// record succeeded proof into current cycle
+	err = v.recordProof(proof.NodeID)
+	if err != nil {
+		return metrics.ReasonRejected, err
 	}
 
-	c.JSON(http.StatusOK, "Verify Proof Success")
+	return metrics.ReasonAccepted, nil
 }
 
 // func (v *GRIDValidator) GetProfitInfo(c *gin.Context) {
@@ -113,25 +354,28 @@ func (v *GRIDValidator) SubmitProofHandler(c *gin.Context) {
 // }
 
 func (v *GRIDValidator) GetWithdrawSignatureHandler(c *gin.Context) {
+	log := middleware.Logger(c)
+
 	address := c.Query("address")
 	amount := c.Query("amount")
 	if len(address) == 0 || len(amount) == 0 {
-		logger.Error("field address or amount is not set")
-		c.AbortWithStatusJSON(400, "field address or amount is not set")
+		log.Error("field address or amount is not set")
+		middleware.AbortWithError(c, logs.BadRequest{Message: "field address or amount is not set"})
 		return
 	}
 
 	amountBig, ok := new(big.Int).SetString(amount, 10)
 	if !ok {
-		logger.Error("field amount is not a decimal number")
-		c.AbortWithStatusJSON(400, "field amount is not a decimal number")
+		log.Error("field amount is not a decimal number")
+		middleware.AbortWithError(c, logs.BadRequest{Message: "field amount is not a decimal number"})
 		return
 	}
 
-	signature, err := v.GenerateWithdrawSignature(address, amountBig)
+	middleware.WithProvider(c, address)
+
+	signature, err := v.GenerateWithdrawSignature(c.Request.Context(), address, amountBig)
 	if err != nil {
-		logger.Error(err.Error())
-		c.AbortWithStatusJSON(500, err.Error())
+		abortQueryError(c, err, "profit of "+address)
 		return
 	}
 
@@ -0,0 +1,72 @@
+package validator
+
+import (
+	"github.com/gridprotocol/validator/core/metrics"
+	"github.com/gridprotocol/validator/core/types"
+	"github.com/gridprotocol/validator/logs"
+)
+
+var (
+	ErrIntakeFull     = logs.Unavailable{Message: "too many proofs under verification"}
+	ErrOutsideWindow  = logs.ProofOutsideWindow{Message: "Failure to submit proof within the proof time"}
+	ErrNotChallenged  = logs.ProofNotChallenged{Message: "node is not challenged in current cycle"}
+	ErrDuplicateProof = logs.ProofDuplicate{Message: "proof of the node is already accepted"}
+	ErrBadPOW         = logs.ProofBadPOW{Message: "Verify Proof Failed"}
+)
+
+// take a slot per proof without blocking, a batch larger than the intake takes all of it,
+// the returned func releases them
+func (v *GRIDValidator) acquireIntake(proofs int) (func(), error) {
+	n := int64(max(min(proofs, v.cfg.IntakeSize), 1))
+	if !v.intake.TryAcquire(n) {
+		return nil, ErrIntakeFull
+	}
+
+	metrics.IntakeInFlight.Add(float64(n))
+
+	return func() {
+		v.intake.Release(n)
+		metrics.IntakeInFlight.Sub(float64(n))
+	}, nil
+}
+
+// start accepting proofs for the challenged nodes
+func (v *GRIDValidator) openResults(cycle int64, resultMap map[types.NodeID]bool) {
+	v.resultLk.Lock()
+	defer v.resultLk.Unlock()
+
+	v.results = resultMap
+	v.resultsCycle = cycle
+}
+
+// stop accepting proofs and return the results
+func (v *GRIDValidator) closeResults() map[types.NodeID]bool {
+	v.resultLk.Lock()
+	defer v.resultLk.Unlock()
+
+	res := v.results
+	v.results = nil
+	v.lastResults = res
+	return res
+}
+
+// mark a verified proof as passed in the current cycle
+func (v *GRIDValidator) recordProof(nodeID types.NodeID) error {
+	v.resultLk.Lock()
+	defer v.resultLk.Unlock()
+
+	if v.results == nil {
+		return ErrOutsideWindow
+	}
+
+	passed, ok := v.results[nodeID]
+	if !ok {
+		return ErrNotChallenged
+	}
+	if passed {
+		return ErrDuplicateProof
+	}
+
+	v.results[nodeID] = true
+	return nil
+}
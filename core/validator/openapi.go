@@ -0,0 +1,68 @@
+package validator
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/xerrors"
+)
+
+// OpenAPI document of the routes in LoadValidatorModule, kept in sync by TestOpenAPIMatchesRoutes
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+func (v *GRIDValidator) GetOpenAPIHandler(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", openAPISpec)
+}
+
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+// check every route under prefix is documented and every documented operation is routed
+func (v *GRIDValidator) CheckOpenAPI(routes gin.RoutesInfo, prefix string) error {
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	err := json.Unmarshal(openAPISpec, &spec)
+	if err != nil {
+		return err
+	}
+
+	documented := make(map[string]bool)
+	for path, ops := range spec.Paths {
+		// {address} -> :address
+		path = pathParam.ReplaceAllString(path, ":$1")
+		for method := range ops {
+			documented[strings.ToUpper(method)+" "+prefix+path] = true
+		}
+	}
+
+	var missing []string
+	for _, route := range routes {
+		if !strings.HasPrefix(route.Path, prefix+"/") {
+			continue
+		}
+
+		key := route.Method + " " + route.Path
+		if !documented[key] {
+			missing = append(missing, "undocumented "+key)
+		}
+		delete(documented, key)
+	}
+
+	for key := range documented {
+		missing = append(missing, "unrouted "+key)
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return xerrors.Errorf("openapi spec out of sync with router: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
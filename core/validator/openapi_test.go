@@ -0,0 +1,43 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/gridprotocol/validator/core/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newTestRouter(t *testing.T) (*GRIDValidator, *gin.Engine) {
+	t.Helper()
+
+	v, err := NewGRIDValidator("dev", nil, store.NewMemoryStore(), DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	v.LoadValidatorModule(router.Group("/v1"))
+	return v, router
+}
+
+// every route of the validator module is documented in openapi.json and the other way round
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	v, router := newTestRouter(t)
+
+	err := v.CheckOpenAPI(router.Routes(), "/v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenAPIReportsUndocumentedRoute(t *testing.T) {
+	v, router := newTestRouter(t)
+	router.GET("/v1/undocumented", v.GetRNDHandler)
+
+	err := v.CheckOpenAPI(router.Routes(), "/v1")
+	if err == nil {
+		t.Fatal("undocumented route is not reported")
+	}
+}
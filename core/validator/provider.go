@@ -0,0 +1,159 @@
+package validator
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/gridprotocol/validator/core/middleware"
+	"github.com/gridprotocol/validator/core/types"
+	"github.com/gridprotocol/validator/logs"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+// provider address from path in checksum case, the spelling the records are stored with.
+// aborts the request if invalid
+func providerParam(c *gin.Context) (string, bool) {
+	address := c.Param("address")
+	if !common.IsHexAddress(address) {
+		middleware.AbortWithError(c, logs.AddressError{Message: "invalid provider address " + address})
+		return "", false
+	}
+
+	return common.HexToAddress(address).Hex(), true
+}
+
+// aborts a request whose query of the record failed, the cause is logged but not returned to the client
+func abortQueryError(c *gin.Context, err error, record string) {
+	if errors.Is(err, logs.ErrNotExist) {
+		middleware.AbortWithError(c, logs.NotFound{Message: record + " does not exist"})
+		return
+	}
+
+	middleware.Logger(c).Error(err.Error())
+	middleware.AbortWithError(c, logs.DataBaseError{Message: "database query failed"})
+}
+
+// get profit record of a provider
+func (v *GRIDValidator) GetProfitHandler(c *gin.Context) {
+	address, ok := providerParam(c)
+	if !ok {
+		return
+	}
+
+	profit, err := v.db.GetProfit(address)
+	if err != nil {
+		abortQueryError(c, err, "profit of "+address)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.ProfitInfo{
+		Address:  address,
+		Balance:  profit.Balance.String(),
+		Profit:   profit.Profit.String(),
+		Penalty:  profit.Penalty.String(),
+		Nonce:    profit.Nonce,
+		LastTime: profit.LastTime,
+		EndTime:  profit.EndTime,
+	})
+}
+
+// get active orders of a provider
+func (v *GRIDValidator) GetOrdersHandler(c *gin.Context) {
+	address, ok := providerParam(c)
+	if !ok {
+		return
+	}
+
+	orders, err := v.listProviderOrders(address)
+	if err != nil {
+		abortQueryError(c, err, "orders of "+address)
+		return
+	}
+
+	c.JSON(http.StatusOK, orders)
+}
+
+// get challenge status of the nodes of a provider
+func (v *GRIDValidator) GetNodesHandler(c *gin.Context) {
+	address, ok := providerParam(c)
+	if !ok {
+		return
+	}
+
+	orders, err := v.listProviderOrders(address)
+	if err != nil {
+		abortQueryError(c, err, "orders of "+address)
+		return
+	}
+
+	summaries, err := v.db.ListNodeSummariesByProvider(address)
+	if err != nil {
+		abortQueryError(c, err, "node results of "+address)
+		return
+	}
+
+	nodes := make(map[uint64]*types.NodeStatus)
+	for _, order := range orders {
+		nodes[order.ID] = &types.NodeStatus{
+			NodeID: types.NodeID{Provider: address, ID: order.ID},
+			Active: true,
+		}
+	}
+
+	for _, summary := range summaries {
+		node, ok := nodes[summary.NodeID]
+		if !ok {
+			node = &types.NodeStatus{
+				NodeID: types.NodeID{Provider: address, ID: summary.NodeID},
+			}
+			nodes[summary.NodeID] = node
+		}
+
+		node.Passed = summary.Passed
+		node.Failed = summary.Failed
+		node.LastCycle = summary.LastCycle
+		node.LastPassed = summary.LastPassed
+	}
+
+	res := make([]types.NodeStatus, 0, len(nodes))
+	for _, node := range nodes {
+		res = append(res, *node)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID < res[j].ID
+	})
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (v *GRIDValidator) listProviderOrders(address string) ([]types.OrderInfo, error) {
+	orders, err := v.db.ListActiveOrders()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]types.OrderInfo, 0)
+	for _, order := range orders {
+		if !sameAddress(order.Provider, address) {
+			continue
+		}
+
+		res = append(res, types.OrderInfo{
+			ID:           order.ID,
+			Provider:     order.Provider,
+			ActivateTime: order.ActivateTime,
+			Probation:    order.Probation,
+			Duration:     order.Duration,
+		})
+	}
+
+	return res, nil
+}
+
+// addresses may differ in checksum case
+func sameAddress(a, b string) bool {
+	return common.HexToAddress(a) == common.HexToAddress(b)
+}
@@ -0,0 +1,145 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gridprotocol/validator/core/metrics"
+	"github.com/gridprotocol/validator/core/store"
+	"github.com/gridprotocol/validator/core/types"
+	"github.com/gridprotocol/validator/logs"
+
+	"golang.org/x/xerrors"
+)
+
+// how to settle the cycles missed during downtime
+type RecoverPolicy string
+
+const (
+	// advance the settle time without reward or penalty, the pending profit is paid in later cycles
+	RecoverNeutral RecoverPolicy = "neutral"
+	// pay the reward of the missed cycle, no penalty
+	RecoverRewardOnly RecoverPolicy = "reward-only"
+	// leave profits untouched, the cycle is only recorded
+	RecoverSkip RecoverPolicy = "skip"
+)
+
+func ParseRecoverPolicy(policy string) (RecoverPolicy, error) {
+	switch RecoverPolicy(policy) {
+	case RecoverNeutral, RecoverRewardOnly, RecoverSkip:
+		return RecoverPolicy(policy), nil
+	}
+
+	return "", xerrors.Errorf("unknown recover policy %q, expect neutral, reward-only or skip", policy)
+}
+
+// settle the cycles between the last settled cycle and the first cycle the validator loop will handle
+func (v *GRIDValidator) Recover(ctx context.Context) error {
+	last, err := v.db.GetLastCycle()
+	if err != nil {
+		// first run, nothing missed
+		if errors.Is(err, logs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	// the current cycle is missed too if its prepare period has passed
+	cycleSeconds := v.cycleSeconds()
+	now := time.Now().Unix()
+	first := now / cycleSeconds
+	if now%cycleSeconds >= int64(v.prepareInterval.Seconds()) {
+		first++
+	}
+
+	from := last.ID + 1
+	if from >= first {
+		return nil
+	}
+
+	if v.cfg.MaxRecoverCycles > 0 && first-from > v.cfg.MaxRecoverCycles {
+		logger.Warnf("missed %d cycles, only the latest %d are recovered", first-from, v.cfg.MaxRecoverCycles)
+		from = first - v.cfg.MaxRecoverCycles
+	}
+
+	logger.Infof("recover cycles [%d, %d) with policy %s", from, first, v.cfg.RecoverPolicy)
+
+	// orders are listed once, nodes activated during downtime are settled as well
+	orders, err := v.db.ListActiveOrders()
+	if err != nil {
+		return err
+	}
+
+	var nodes []types.NodeID
+	for _, order := range orders {
+		nodes = append(nodes, types.NodeID{
+			Provider: order.Provider,
+			ID:       order.ID,
+		})
+	}
+
+	for id := from; id < first; id++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		err = v.recoverCycle(ctx, id, nodes)
+		if errors.Is(err, logs.ErrAlreadyExist) {
+			logger.Infof("cycle %d is recovered by another validator", id)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// settle one missed cycle with the recover policy
+func (v *GRIDValidator) recoverCycle(ctx context.Context, id int64, nodes []types.NodeID) error {
+	start := id * v.cycleSeconds()
+	policy := v.cfg.RecoverPolicy
+
+	cycle := store.Cycle{
+		ID:        id,
+		StartTime: time.Unix(start, 0),
+		Status:    store.CycleRecovered,
+		Policy:    string(policy),
+	}
+
+	settleNodes := policy == RecoverNeutral || policy == RecoverRewardOnly
+	if settleNodes {
+		cycle.Challenged = len(nodes)
+	} else {
+		cycle.Status = store.CycleSkipped
+	}
+
+	logger.Debugf("cycle %d %s", id, cycle.Status)
+
+	// the cycle and the profit changes are written together, so a failed cycle is recovered again
+	cycle.SettledAt = time.Now()
+	err := v.db.SettleCycle(ctx, &cycle, nil, func(profits store.ProfitReader) ([]store.Ledger, error) {
+		if !settleNodes {
+			return nil, nil
+		}
+
+		s := newSettlement(profits, id, start)
+		for _, nodeID := range nodes {
+			err := s.settleNode(ctx, nodeID, policy == RecoverRewardOnly, false, store.ReasonRecover+string(policy))
+			if err != nil {
+				return nil, err
+			}
+		}
+		return s.ledger, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	metrics.CyclesTotal.WithLabelValues(cycle.Status).Inc()
+	return nil
+}
@@ -0,0 +1,156 @@
+package validator
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gridprotocol/validator/core/middleware"
+	"github.com/gridprotocol/validator/core/types"
+	"github.com/gridprotocol/validator/logs"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultStatsCycles = 30
+	topFailingLimit    = 10
+)
+
+// windows the stats are computed over, an hour, a day and a week of cycles besides the last one.
+// a fixed set bounds the cache and the queries a client can cause
+var statsWindows = []int64{1, defaultStatsCycles, 720, 5040}
+
+// stats only change when a cycle is settled, keep them until the next one
+type statsCache struct {
+	cycle int64
+	stats map[int64]types.Stats
+	// ledger totals of the cycle, summed once for all windows
+	summed       bool
+	totalReward  string
+	totalPenalty string
+}
+
+// compute stats over the last cycles, cached per settled cycle. cycles is one of the stats windows
+func (v *GRIDValidator) GetStats(cycles int64) (types.Stats, error) {
+	var last int64
+	cycle, err := v.db.GetLastCycle()
+	if err == nil {
+		last = cycle.ID
+	} else if !errors.Is(err, logs.ErrNotExist) {
+		return types.Stats{}, err
+	}
+
+	v.statsLk.Lock()
+	defer v.statsLk.Unlock()
+
+	if v.stats.cycle != last || v.stats.stats == nil {
+		v.stats = statsCache{
+			cycle: last,
+			stats: make(map[int64]types.Stats),
+		}
+	}
+
+	stats, ok := v.stats.stats[cycles]
+	if ok {
+		return stats, nil
+	}
+
+	if !v.stats.summed {
+		reward, penalty, err := v.db.SumLedger()
+		if err != nil {
+			return types.Stats{}, err
+		}
+		v.stats.summed = true
+		v.stats.totalReward = reward.String()
+		v.stats.totalPenalty = penalty.String()
+	}
+
+	stats, err = v.computeStats(last, cycles)
+	if err != nil {
+		return types.Stats{}, err
+	}
+	stats.TotalReward = v.stats.totalReward
+	stats.TotalPenalty = v.stats.totalPenalty
+
+	v.stats.stats[cycles] = stats
+	return stats, nil
+}
+
+func (v *GRIDValidator) computeStats(last, cycles int64) (types.Stats, error) {
+	stats := types.Stats{
+		Cycle:      last,
+		Cycles:     cycles,
+		TopFailing: []types.ProviderFailures{},
+	}
+
+	orders, err := v.db.ListActiveOrders()
+	if err != nil {
+		return types.Stats{}, err
+	}
+
+	providers := make(map[string]struct{})
+	for _, order := range orders {
+		providers[order.Provider] = struct{}{}
+	}
+	stats.ActiveOrders = len(orders)
+	stats.Providers = len(providers)
+
+	from := last - cycles + 1
+	count, err := v.db.CountResults(from)
+	if err != nil {
+		return types.Stats{}, err
+	}
+
+	stats.ChallengedNodes = count.Nodes
+	stats.Challenges = count.Challenges
+	stats.Passed = count.Passed
+	stats.Failed = count.Challenges - count.Passed
+	if count.Challenges > 0 {
+		stats.PassRate = float64(count.Passed) / float64(count.Challenges)
+	}
+
+	top, err := v.db.ListTopFailing(from, topFailingLimit)
+	if err != nil {
+		return types.Stats{}, err
+	}
+	for _, provider := range top {
+		stats.TopFailing = append(stats.TopFailing, types.ProviderFailures{
+			Provider: provider.Provider,
+			Failed:   provider.Failed,
+		})
+	}
+
+	return stats, nil
+}
+
+// get validator wide statistics
+func (v *GRIDValidator) GetStatsHandler(c *gin.Context) {
+	cycles := int64(defaultStatsCycles)
+	if value := c.Query("cycles"); value != "" {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || !slices.Contains(statsWindows, n) {
+			middleware.AbortWithError(c, logs.BadRequest{Message: "field cycles should be one of " + formatWindows()})
+			return
+		}
+		cycles = n
+	}
+
+	stats, err := v.GetStats(cycles)
+	if err != nil {
+		abortQueryError(c, err, "stats")
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+func formatWindows() string {
+	windows := make([]string, 0, len(statsWindows))
+	for _, window := range statsWindows {
+		windows = append(windows, strconv.FormatInt(window, 10))
+	}
+	return strings.Join(windows, ", ")
+}
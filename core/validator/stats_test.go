@@ -0,0 +1,61 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/gridprotocol/validator/core/types"
+)
+
+func TestGetStats(t *testing.T) {
+	v, _ := newTestValidator(t, DefaultConfig())
+	res := map[types.NodeID]bool{paidNode: true, pendingNode: false}
+	for cycle := int64(1); cycle <= 3; cycle++ {
+		err := settleTestCycle(v, cycle, res)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	last, err := v.GetStats(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last.Cycle != 3 || last.Challenges != 2 || last.Passed != 1 || last.Failed != 1 || last.PassRate != 0.5 {
+		t.Fatalf("stats of the last cycle %+v", last)
+	}
+
+	stats, err := v.GetStats(defaultStatsCycles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Challenges != 6 || stats.Failed != 3 || stats.ChallengedNodes != 2 || stats.ActiveOrders != 2 || stats.Providers != 2 {
+		t.Fatalf("stats of %d cycles %+v", defaultStatsCycles, stats)
+	}
+	if len(stats.TopFailing) != 1 || stats.TopFailing[0] != (types.ProviderFailures{Provider: pendingNode.Provider, Failed: 3}) {
+		t.Fatalf("top failing %+v", stats.TopFailing)
+	}
+	// 1% of the remain profit in each cycle
+	if stats.TotalReward != "10000" || stats.TotalPenalty != "297" {
+		t.Fatalf("total reward %s penalty %s, expect 10000 and 297", stats.TotalReward, stats.TotalPenalty)
+	}
+
+	// the cached stats are replaced once the next cycle is settled
+	err = settleTestCycle(v, 4, res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last, err = v.GetStats(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last.Cycle != 4 {
+		t.Fatalf("stats are of cycle %d, expect 4", last.Cycle)
+	}
+	stats, err = v.GetStats(defaultStatsCycles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Challenges != 8 || stats.TotalPenalty != "394" {
+		t.Fatalf("stats after cycle 4 %+v", stats)
+	}
+}
@@ -0,0 +1,46 @@
+package validator
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/gridprotocol/validator/core/store"
+)
+
+// storage the validator needs. orders and profits are synced from chain by the dumper,
+// cycles, results and ledger are owned by the validator.
+// getters return logs.ErrNotExist for missing records
+type Store interface {
+	// check the storage is reachable
+	Ping(ctx context.Context) error
+
+	// active orders of all providers
+	ListActiveOrders() ([]store.Order, error)
+	GetOrderCount(provider string) (int64, error)
+
+	GetProfit(provider string) (store.Profit, error)
+
+	// record a handled cycle with its node results and the ledger computed by settle, and apply the ledger
+	// to the profits. a failed settlement leaves the profits unchanged and the cycle unrecorded,
+	// logs.ErrAlreadyExist is returned if the cycle is recorded
+	SettleCycle(ctx context.Context, cycle *store.Cycle, results []store.NodeResult, settle store.SettleFunc) error
+	// the latest handled cycle
+	GetLastCycle() (store.Cycle, error)
+
+	ListNodeSummariesByProvider(provider string) ([]store.NodeSummary, error)
+	CountResults(fromCycle int64) (store.ResultCount, error)
+	// providers with the most failed proofs since fromCycle
+	ListTopFailing(fromCycle int64, limit int) ([]store.ProviderFailures, error)
+
+	// total reward and penalty of all ledger entries
+	SumLedger() (*big.Int, *big.Int, error)
+
+	// cycles, results and ledger of a cycle range for exports
+	store.Exporter
+}
+
+var (
+	_ Store = (*store.DumperStore)(nil)
+	_ Store = (*store.PostgresStore)(nil)
+	_ Store = (*store.MemoryStore)(nil)
+)
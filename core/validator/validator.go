@@ -4,37 +4,96 @@ import (
 	"context"
 	"crypto/ecdsa"
 	"encoding/binary"
+	"encoding/hex"
+	"errors"
 	"math/big"
 	"math/rand"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/gridprotocol/dumper/database"
+	"github.com/gridprotocol/validator/core/metrics"
+	"github.com/gridprotocol/validator/core/store"
+	"github.com/gridprotocol/validator/core/tracing"
 	"github.com/gridprotocol/validator/core/types"
 	"github.com/gridprotocol/validator/logs"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/sync/semaphore"
+	"golang.org/x/xerrors"
 )
 
 var logger = logs.Logger("grid validator")
 
-// var proofChan = make(chan Proof, 100)
-var resultChan = make(chan types.Result, 100)
 var RND [32]byte
 
+type Config struct {
+	// policy to settle the cycles missed during downtime
+	RecoverPolicy RecoverPolicy
+	// max number of missed cycles settled at startup, 0 means no limit
+	MaxRecoverCycles int64
+	// settle the started cycle before stopping
+	Drain bool
+	// max proofs verified at the same time, more are rejected
+	IntakeSize int
+}
+
+func DefaultConfig() Config {
+	return Config{
+		RecoverPolicy:    RecoverNeutral,
+		MaxRecoverCycles: 720,
+		Drain:            true,
+		IntakeSize:       64,
+	}
+}
+
 type GRIDValidator struct {
 	last            int64
 	prepareInterval time.Duration
 	proveInterval   time.Duration
 	waitInterval    time.Duration
 
-	sk *ecdsa.PrivateKey
-
-	done  chan struct{}
-	doned bool
+	cfg Config
+	sk  *ecdsa.PrivateKey
+	db  Store
+
+	// cycle being handled, and the last settled or skipped one
+	cycle     int64
+	lastCycle int64
+
+	// slots of proofs under verification, one per proof
+	intake *semaphore.Weighted
+	// results of the cycle in prove period, nil outside
+	resultLk     sync.Mutex
+	results      map[types.NodeID]bool
+	resultsCycle int64
+	lastResults  map[types.NodeID]bool
+
+	// operator controls
+	paused       atomic.Bool
+	trigger      chan struct{}
+	difficultyLk sync.RWMutex
+	difficulty   map[types.NodeID]int
+
+	events *eventBroker
+
+	statsLk sync.Mutex
+	stats   statsCache
+
+	done     chan struct{}
+	stopOnce sync.Once
+	started  atomic.Bool
+	stopped  chan struct{}
 }
 
-func NewGRIDValidator(chain string, sk *ecdsa.PrivateKey) (*GRIDValidator, error) {
+func NewGRIDValidator(chain string, sk *ecdsa.PrivateKey, db Store, cfg Config) (*GRIDValidator, error) {
+	// no proof could be verified with an empty intake
+	if cfg.IntakeSize < 1 {
+		return nil, xerrors.Errorf("intake size must be at least 1, got %d", cfg.IntakeSize)
+	}
+
 	// get time information from contract
 	prepareInterval := 10 * time.Second
 	proveInterval := 10 * time.Second
@@ -45,29 +104,68 @@ func NewGRIDValidator(chain string, sk *ecdsa.PrivateKey) (*GRIDValidator, error
 		proveInterval:   proveInterval,
 		waitInterval:    waitInterval,
 
-		sk: sk,
+		cfg: cfg,
+		sk:  sk,
+		db:  db,
 
-		done:  make(chan struct{}),
-		doned: false,
+		intake: semaphore.NewWeighted(int64(cfg.IntakeSize)),
+		events: newEventBroker(),
+
+		trigger:    make(chan struct{}),
+		difficulty: make(map[types.NodeID]int),
+
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
 	}, nil
 }
 
-func (v *GRIDValidator) Start(ctx context.Context) {
+// run challenge cycles until ctx is canceled or Stop is called
+func (v *GRIDValidator) Start(ctx context.Context) error {
+	v.started.Store(true)
+	defer close(v.stopped)
+	// end event streams
+	defer v.events.Close()
+
+	// Stop cancels the loop too
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-v.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	// settle the cycles missed while the validator was down
+	err := v.Recover(ctx)
+	if err != nil {
+		logger.Error(err.Error())
+	}
+
+	last, err := v.db.GetLastCycle()
+	if err == nil {
+		v.lastCycle = last.ID
+	}
+
 	for {
 		// 等待下一个prepare时期
 		wait, nextTime := v.CalculateWatingToPrepare()
-		select {
-		case <-ctx.Done():
-			v.doned = true
-			return
-		case <-v.done:
-			v.doned = true
-			return
-		case <-time.After(wait):
+		manual, ok := v.waitOrTrigger(ctx, wait, 0)
+		if !ok {
+			return nil
 		}
 
+		// in drain mode, a started cycle is settled before exit
+		cycleCtx := ctx
+		if v.cfg.Drain {
+			cycleCtx = context.WithoutCancel(ctx)
+		}
+
+		v.publishPhase(v.currentCycle(), PhasePrepare)
+
 		// generate a random value
-		err := v.GenerateRND(ctx)
+		err = v.GenerateRND(cycleCtx)
 		if err != nil {
 			logger.Error(err.Error())
 			continue
@@ -75,139 +173,343 @@ func (v *GRIDValidator) Start(ctx context.Context) {
 
 		// 等待下一个prove时期
 		wait, _ = v.CalculateWatingToProve()
-		select {
-		case <-ctx.Done():
-			return
-		case <-v.done:
-			v.doned = true
-			return
-		case <-time.After(wait):
+		triggered, ok := v.waitOrTrigger(cycleCtx, wait, v.prepareInterval)
+		if !ok {
+			return nil
+		}
+		manual = manual || triggered
+
+		// a manual cycle takes the next id, the regular cycle it pulled forward is not run again
+		v.cycle = v.last / v.cycleSeconds()
+		if v.cycle <= v.lastCycle {
+			if !manual {
+				continue
+			}
+			v.cycle = v.lastCycle + 1
+		}
+
+		if v.Paused() && !manual {
+			err = v.skipCycle(cycleCtx)
+			if err != nil {
+				logger.Error(err.Error())
+			}
+			v.publishPhase(v.cycle, PhaseWait)
+			continue
 		}
 
 		// get nodes list with order
-		resultMap, err := v.GetChallengeNode(ctx)
+		resultMap, err := v.GetChallengeNode(cycleCtx)
 		if err != nil {
 			logger.Error(err.Error())
 			continue
 		}
 
-		// receive succeeded proof from chan and set resultMap
-		res, err := v.HandleResult(ctx, resultMap)
+		// collect succeeded proofs into resultMap
+		res, err := v.HandleResult(cycleCtx, resultMap)
 		if err != nil {
 			logger.Error(err.Error())
 			continue
 		}
 
+		// the prove period was cut short, the nodes that had no time to prove must not be penalized.
+		// nothing is recorded, the cycle is settled by recovery after restart
+		if ctx.Err() != nil && !v.cfg.Drain {
+			logger.Infof("stop validator in the prove period, cycle %d is not settled", v.cycle)
+			return nil
+		}
+
 		logger.Info("Start update profits")
+		settleStart := time.Now()
 
-		// add penalty for each failed proof
-		err = v.AddPenalty(ctx, res)
-		if err != nil {
+		err = v.settle(cycleCtx, res)
+		switch {
+		case errors.Is(err, logs.ErrAlreadyExist):
+			logger.Infof("cycle %d is settled by another validator", v.cycle)
+		case err != nil:
 			logger.Error(err.Error())
+			metrics.SettlementErrors.Inc()
 			continue
+		default:
+			metrics.SettlementDuration.Observe(time.Since(settleStart).Seconds())
+			v.publishSettlement(v.cycle, res, time.Since(settleStart))
 		}
 
+		v.lastCycle = v.cycle
 		v.last = nextTime
+		if manual {
+			// back to the regular schedule
+			v.last = time.Now().Unix() / v.cycleSeconds() * v.cycleSeconds()
+		}
+
+		if ctx.Err() != nil {
+			logger.Info("current cycle settled, stop validator")
+			return nil
+		}
 	}
 }
 
-// receive proof from chan and set resultMap
+// open resultMap for proofs during the prove period, then return the collected results
 func (v *GRIDValidator) HandleResult(ctx context.Context, resultMap map[types.NodeID]bool) (map[types.NodeID]bool, error) {
-	var channel = make(chan struct{})
-
 	logger.Info("start handle result")
 
-	// send quit signal
-	go func() {
-		select {
-		case <-ctx.Done():
-			channel <- struct{}{}
-			return
-		case <-v.done:
-			channel <- struct{}{}
-			return
-		case <-time.After(v.proveInterval):
-			channel <- struct{}{}
-			return
-		}
-	}()
+	cycle := v.cycle
+	v.openResults(cycle, resultMap)
 
-	for {
-		select {
-		// quit
-		case <-channel:
-			logger.Info("end handle result")
-			return resultMap, nil
-
-		// receive succeeded proof result
-		case result := <-resultChan:
-			if _, ok := resultMap[result.NodeID]; ok {
-				resultMap[result.NodeID] = true
-			}
-		}
+	v.publishPhase(cycle, PhaseProve)
+	v.events.Publish(types.Event{
+		Type:  types.EventRND,
+		Cycle: cycle,
+		RND:   hex.EncodeToString(RND[:]),
+	})
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(v.proveInterval):
 	}
 
+	res := v.closeResults()
+	v.publishPhase(cycle, PhaseWait)
+
+	logger.Info("end handle result")
+	return res, nil
 }
 
-// add penalty for each failed proof, update profit info in db
-func (v *GRIDValidator) AddPenalty(ctx context.Context, res map[types.NodeID]bool) error {
-	for nodeID, result := range res {
+// settle profits of the collected results and record the cycle
+func (v *GRIDValidator) settle(ctx context.Context, res map[types.NodeID]bool) (err error) {
+	ctx, span := tracing.Start(ctx, "settle cycle",
+		attribute.Int64("cycle", v.cycle),
+		attribute.Int("nodes", len(res)),
+	)
+	defer tracing.End(span, &err)
+
+	// record the settled cycle and node results with the profit changes
+	return v.SaveResult(ctx, res)
+}
 
-		// get profit from db
-		profitInfo, err := database.GetProfitByAddress(nodeID.Provider)
+// add penalty for each failed proof into the settlement of the cycle
+func (v *GRIDValidator) AddPenalty(ctx context.Context, s *settlement, res map[types.NodeID]bool) error {
+	for nodeID, result := range res {
+		err := s.settleNode(ctx, nodeID, true, !result, store.ReasonChallenge)
 		if err != nil {
 			return err
 		}
+	}
 
-		// calc reward
-		var reward = new(big.Int)
-		if v.last <= profitInfo.LastTime.Unix() {
-			reward.SetInt64(0)
-		} else if v.last >= profitInfo.EndTime.Unix() {
-			reward.Set(profitInfo.Profit)
-		} else if profitInfo.LastTime.Unix() >= profitInfo.EndTime.Unix() {
-			reward.SetInt64(0)
+	return nil
+}
+
+// record node results and the settled cycle into db, the profits are changed with them.
+// fails with logs.ErrAlreadyExist if another validator sharing the db settled the cycle
+func (v *GRIDValidator) SaveResult(ctx context.Context, res map[types.NodeID]bool) (err error) {
+	ctx, span := tracing.Start(ctx, "save result")
+	defer tracing.End(span, &err)
+
+	cycle := store.Cycle{
+		ID:         v.cycle,
+		StartTime:  time.Unix(v.last, 0),
+		Status:     store.CycleSettled,
+		Challenged: len(res),
+	}
+
+	results := make([]store.NodeResult, 0, len(res))
+	for nodeID, result := range res {
+		results = append(results, store.NodeResult{
+			Cycle:    cycle.ID,
+			Provider: nodeID.Provider,
+			NodeID:   nodeID.ID,
+			Passed:   result,
+		})
+
+		if result {
+			cycle.Passed++
 		} else {
-			reward.Mul(profitInfo.Profit, big.NewInt((v.last-profitInfo.LastTime.Unix())/(profitInfo.EndTime.Unix()-profitInfo.LastTime.Unix())))
+			cycle.Failed++
 		}
+	}
 
-		// remain := profitInfo.Profit - reward
-		remain := new(big.Int).Sub(profitInfo.Profit, reward)
+	cycle.SettledAt = time.Now()
+	err = v.db.SettleCycle(ctx, &cycle, results, func(profits store.ProfitReader) ([]store.Ledger, error) {
+		// add penalty for each failed proof
+		s := newSettlement(profits, cycle.ID, v.last)
+		err := v.AddPenalty(ctx, s, res)
+		return s.ledger, err
+	})
+	if err != nil {
+		return err
+	}
 
-		// calc penalty if proof failed, 1% of remain per failure proof
-		var penalty = big.NewInt(0)
-		if !result {
-			// penalty = remain / 100
-			penalty.Div(remain, big.NewInt(100))
-		}
+	metrics.CyclesTotal.WithLabelValues(store.CycleSettled).Inc()
+	for status, n := range map[string]int{
+		"challenged": cycle.Challenged,
+		"passed":     cycle.Passed,
+		"failed":     cycle.Failed,
+	} {
+		metrics.CycleNodes.WithLabelValues(status).Set(float64(n))
+		metrics.CycleNodesTotal.WithLabelValues(status).Add(float64(n))
+	}
 
-		profitInfo.LastTime = time.Unix(v.last, 0)
-		profitInfo.Balance.Add(profitInfo.Balance, reward)
-		profitInfo.Profit.Sub(remain, penalty)
-		profitInfo.Penalty.Add(profitInfo.Penalty, penalty)
+	return nil
+}
+
+// profit changes of a cycle, written with the cycle by Store.SettleCycle
+type settlement struct {
+	db    store.ProfitReader
+	cycle int64
+	// cycle start, profits are settled up to it
+	at int64
+	// profits read once per provider and changed by each of its nodes
+	profits map[string]store.Profit
+	ledger  []store.Ledger
+}
 
-		logger.Debugf("Balance: %d, Profit: %d, penalty: %d", profitInfo.Balance, profitInfo.Profit, profitInfo.Penalty)
+func newSettlement(db store.ProfitReader, cycle, at int64) *settlement {
+	return &settlement{
+		db:      db,
+		cycle:   cycle,
+		at:      at,
+		profits: make(map[string]store.Profit),
+	}
+}
 
-		// update profit into db
-		err = profitInfo.UpdateProfit()
+// settle the profit of the node's provider up to the time at, penalty is 1% of the remain profit.
+// the change is recorded into ledger
+func (s *settlement) settleNode(ctx context.Context, nodeID types.NodeID, withReward, withPenalty bool, reason string) (err error) {
+	_, span := tracing.Start(ctx, "settle node",
+		attribute.String("provider", nodeID.Provider),
+		attribute.Int64("node", int64(nodeID.ID)),
+		attribute.String("reason", reason),
+	)
+	defer tracing.End(span, &err)
+
+	// get profit from db, or as settled by another node of the provider
+	profitInfo, ok := s.profits[nodeID.Provider]
+	if !ok {
+		profitInfo, err = s.db.GetProfit(nodeID.Provider)
 		if err != nil {
 			return err
 		}
 	}
 
+	var reward = big.NewInt(0)
+	if withReward {
+		reward = calcReward(profitInfo.Profit, profitInfo.LastTime, profitInfo.EndTime, s.at)
+	}
+
+	// remain := profitInfo.Profit - reward
+	remain := new(big.Int).Sub(profitInfo.Profit, reward)
+
+	// calc penalty if proof failed, 1% of remain per failure proof
+	var penalty = big.NewInt(0)
+	if withPenalty {
+		// penalty = remain / 100
+		penalty.Div(remain, big.NewInt(100))
+	}
+
+	profitInfo.LastTime = time.Unix(s.at, 0)
+	profitInfo.Balance.Add(profitInfo.Balance, reward)
+	profitInfo.Profit.Sub(remain, penalty)
+	profitInfo.Penalty.Add(profitInfo.Penalty, penalty)
+
+	logger.Debugf("Balance: %d, Profit: %d, penalty: %d", profitInfo.Balance, profitInfo.Profit, profitInfo.Penalty)
+
+	s.profits[nodeID.Provider] = profitInfo
+
+	s.ledger = append(s.ledger, store.Ledger{
+		Cycle:    s.cycle,
+		Provider: nodeID.Provider,
+		NodeID:   nodeID.ID,
+		Reason:   reason,
+		Reward:   reward.String(),
+		Penalty:  penalty.String(),
+		Balance:  profitInfo.Balance.String(),
+		Profit:   profitInfo.Profit.String(),
+	})
 	return nil
 }
 
+// calc reward from last settle time to the time at
+func calcReward(profit *big.Int, lastTime, endTime time.Time, at int64) *big.Int {
+	var reward = new(big.Int)
+	if at <= lastTime.Unix() {
+		reward.SetInt64(0)
+	} else if at >= endTime.Unix() {
+		reward.Set(profit)
+	} else if lastTime.Unix() >= endTime.Unix() {
+		reward.SetInt64(0)
+	} else {
+		reward.Mul(profit, big.NewInt((at-lastTime.Unix())/(endTime.Unix()-lastTime.Unix())))
+	}
+
+	return reward
+}
+
+// stop the validator loop and wait for it to exit
 func (v *GRIDValidator) Stop() {
-	close(v.done)
+	v.stopOnce.Do(func() {
+		close(v.done)
+	})
 
-	for !v.doned {
-		time.Sleep(200 * time.Millisecond)
+	if v.started.Load() {
+		<-v.stopped
 	}
 }
 
+// closed after the validator loop exits
+func (v *GRIDValidator) Stopped() <-chan struct{} {
+	return v.stopped
+}
+
+// storage of the validator
+func (v *GRIDValidator) Store() Store {
+	return v.db
+}
+
+// whether the validator loop is running
+func (v *GRIDValidator) Alive() bool {
+	if !v.started.Load() {
+		return false
+	}
+
+	select {
+	case <-v.stopped:
+		return false
+	default:
+		return true
+	}
+}
+
+// phase of the current cycle
+const (
+	PhasePrepare = "prepare"
+	PhaseProve   = "prove"
+	PhaseWait    = "wait"
+)
+
+// cycles are aligned to unix time, so the phase only depends on now
+func (v *GRIDValidator) Phase() string {
+	over := time.Now().Unix() % v.cycleSeconds()
+	if over < int64(v.prepareInterval.Seconds()) {
+		return PhasePrepare
+	}
+	if over <= int64((v.prepareInterval + v.proveInterval).Seconds()) {
+		return PhaseProve
+	}
+
+	return PhaseWait
+}
+
+// cycle of now
+func (v *GRIDValidator) currentCycle() int64 {
+	return time.Now().Unix() / v.cycleSeconds()
+}
+
+// length of a challenge cycle in seconds
+func (v *GRIDValidator) cycleSeconds() int64 {
+	return int64((v.prepareInterval + v.proveInterval + v.waitInterval).Seconds())
+}
+
 func (v *GRIDValidator) IsProveTime() bool {
-	challengeCycleSeconds := int64((v.prepareInterval + v.proveInterval + v.waitInterval).Seconds())
+	challengeCycleSeconds := v.cycleSeconds()
 	now := time.Now().Unix()
 	duration := now - v.last
 	over := duration % challengeCycleSeconds
@@ -220,7 +522,7 @@ func (v *GRIDValidator) IsProveTime() bool {
 
 // wait time
 func (v *GRIDValidator) CalculateWatingToPrepare() (time.Duration, int64) {
-	challengeCycleSeconds := int64((v.prepareInterval + v.proveInterval + v.waitInterval).Seconds())
+	challengeCycleSeconds := v.cycleSeconds()
 	now := time.Now().Unix()
 	duration := now - v.last
 	over := duration % challengeCycleSeconds
@@ -237,7 +539,7 @@ func (v *GRIDValidator) CalculateWatingToPrepare() (time.Duration, int64) {
 
 // wait time
 func (v *GRIDValidator) CalculateWatingToProve() (time.Duration, int64) {
-	challengeCycleSeconds := int64((v.prepareInterval + v.proveInterval + v.waitInterval).Seconds())
+	challengeCycleSeconds := v.cycleSeconds()
 	now := time.Now().Unix()
 	duration := now - v.last
 	over := duration % challengeCycleSeconds
@@ -266,8 +568,11 @@ func (v *GRIDValidator) GenerateRND(ctx context.Context) error {
 }
 
 // get all nodes with order
-func (v *GRIDValidator) GetChallengeNode(ctx context.Context) (map[types.NodeID]bool, error) {
-	orders, err := database.ListAllActivedOrder()
+func (v *GRIDValidator) GetChallengeNode(ctx context.Context) (_ map[types.NodeID]bool, err error) {
+	_, span := tracing.Start(ctx, "list challenge nodes")
+	defer tracing.End(span, &err)
+
+	orders, err := v.db.ListActiveOrders()
 	if err != nil {
 		return nil, err
 	}
@@ -279,7 +584,7 @@ func (v *GRIDValidator) GetChallengeNode(ctx context.Context) (map[types.NodeID]
 	for _, order := range orders {
 		resultMap[types.NodeID{
 			Provider: order.Provider,
-			ID:       order.Id,
+			ID:       order.ID,
 		}] = false
 	}
 
@@ -287,10 +592,13 @@ func (v *GRIDValidator) GetChallengeNode(ctx context.Context) (map[types.NodeID]
 }
 
 // generate signature
-func (v *GRIDValidator) GenerateWithdrawSignature(address string, amount *big.Int) ([]byte, error) {
-	profit, err := database.GetProfitByAddress(address)
+func (v *GRIDValidator) GenerateWithdrawSignature(ctx context.Context, address string, amount *big.Int) (_ []byte, err error) {
+	_, span := tracing.Start(ctx, "withdraw signature", attribute.String("provider", address))
+	defer tracing.End(span, &err)
+
+	profit, err := v.db.GetProfit(address)
 	if err != nil {
-		return nil, err
+		return nil, xerrors.Errorf("profit of %s: %w", address, err)
 	}
 
 	// get nonce
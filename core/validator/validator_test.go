@@ -0,0 +1,257 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/gridprotocol/validator/core/store"
+	"github.com/gridprotocol/validator/core/types"
+	"github.com/gridprotocol/validator/logs"
+)
+
+var (
+	// paid in full by its first settlement, its orders have ended
+	paidNode = types.NodeID{Provider: "paid", ID: 1}
+	// paid nothing before its end, penalized when it fails
+	pendingNode = types.NodeID{Provider: "pending", ID: 1}
+)
+
+func newTestValidator(t *testing.T, cfg Config) (*GRIDValidator, *store.MemoryStore) {
+	t.Helper()
+
+	db := store.NewMemoryStore()
+	for _, node := range []types.NodeID{paidNode, pendingNode} {
+		db.AddOrder(store.Order{ID: node.ID, Provider: node.Provider})
+	}
+	db.SetProfit(store.Profit{
+		Address:  paidNode.Provider,
+		Profit:   big.NewInt(10000),
+		LastTime: time.Unix(0, 0),
+		EndTime:  time.Unix(100, 0),
+	})
+	db.SetProfit(store.Profit{
+		Address:  pendingNode.Provider,
+		Profit:   big.NewInt(10000),
+		LastTime: time.Unix(0, 0),
+		EndTime:  time.Now().Add(24 * time.Hour),
+	})
+
+	v, err := NewGRIDValidator("dev", nil, db, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v, db
+}
+
+// settle the results as the validator loop does for the cycle
+func settleTestCycle(v *GRIDValidator, cycle int64, res map[types.NodeID]bool) error {
+	v.cycle = cycle
+	v.last = cycle * v.cycleSeconds()
+	return v.settle(context.Background(), res)
+}
+
+func checkProfit(t *testing.T, db *store.MemoryStore, provider string, balance, profit, penalty int64) store.Profit {
+	t.Helper()
+
+	p, err := db.GetProfit(provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Balance.Int64() != balance || p.Profit.Int64() != profit || p.Penalty.Int64() != penalty {
+		t.Fatalf("profit of %s is balance %s profit %s penalty %s, expect %d %d %d",
+			provider, p.Balance, p.Profit, p.Penalty, balance, profit, penalty)
+	}
+	return p
+}
+
+func listLedger(t *testing.T, db *store.MemoryStore) []store.Ledger {
+	t.Helper()
+
+	var ledger []store.Ledger
+	err := db.EachLedger(context.Background(), store.ExportFilter{}, func(entry store.Ledger) error {
+		ledger = append(ledger, entry)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ledger
+}
+
+func listCycles(t *testing.T, db *store.MemoryStore) []store.Cycle {
+	t.Helper()
+
+	var cycles []store.Cycle
+	err := db.EachCycle(context.Background(), store.ExportFilter{}, func(cycle store.Cycle) error {
+		cycles = append(cycles, cycle)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cycles
+}
+
+func TestSettleCycle(t *testing.T) {
+	v, db := newTestValidator(t, DefaultConfig())
+	res := map[types.NodeID]bool{paidNode: true, pendingNode: false}
+
+	err := settleTestCycle(v, 1, res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paid := checkProfit(t, db, paidNode.Provider, 10000, 0, 0)
+	if paid.LastTime.Unix() != v.cycleSeconds() {
+		t.Fatalf("profit is settled up to %d, expect the cycle start %d", paid.LastTime.Unix(), v.cycleSeconds())
+	}
+	// 1% of the remain profit
+	checkProfit(t, db, pendingNode.Provider, 0, 9900, 100)
+
+	cycle, err := db.GetLastCycle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cycle.ID != 1 || cycle.Status != store.CycleSettled || cycle.Challenged != 2 || cycle.Passed != 1 || cycle.Failed != 1 {
+		t.Fatalf("recorded cycle %+v", cycle)
+	}
+	if n := len(listLedger(t, db)); n != 2 {
+		t.Fatalf("%d ledger entries, expect 2", n)
+	}
+
+	// settled by another validator sharing the store
+	err = settleTestCycle(v, 1, res)
+	if !errors.Is(err, logs.ErrAlreadyExist) {
+		t.Fatalf("settling the cycle again returned %v, expect %v", err, logs.ErrAlreadyExist)
+	}
+	checkProfit(t, db, pendingNode.Provider, 0, 9900, 100)
+	if n := len(listLedger(t, db)); n != 2 {
+		t.Fatalf("%d ledger entries after settling again, expect 2", n)
+	}
+}
+
+// a failed settlement changes no profit and records nothing, so the cycle is recovered later
+func TestSettleCycleMissingProfit(t *testing.T) {
+	v, db := newTestValidator(t, DefaultConfig())
+	res := map[types.NodeID]bool{
+		pendingNode:                  false,
+		{Provider: "missing", ID: 1}: true,
+	}
+
+	err := settleTestCycle(v, 1, res)
+	if !errors.Is(err, logs.ErrNotExist) {
+		t.Fatalf("settlement returned %v, expect %v", err, logs.ErrNotExist)
+	}
+
+	checkProfit(t, db, pendingNode.Provider, 0, 10000, 0)
+	_, err = db.GetLastCycle()
+	if !errors.Is(err, logs.ErrNotExist) {
+		t.Fatalf("cycle is recorded: %v", err)
+	}
+	if n := len(listLedger(t, db)); n != 0 {
+		t.Fatalf("%d ledger entries are kept", n)
+	}
+}
+
+// record the last cycle handled before the validator stopped
+func stopAt(t *testing.T, v *GRIDValidator, db *store.MemoryStore, missed int64) int64 {
+	t.Helper()
+
+	id := time.Now().Unix()/v.cycleSeconds() - missed
+	err := db.SettleCycle(context.Background(), &store.Cycle{ID: id, Status: store.CycleSettled}, nil,
+		func(store.ProfitReader) ([]store.Ledger, error) {
+			return nil, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestParseRecoverPolicy(t *testing.T) {
+	for _, policy := range []RecoverPolicy{RecoverNeutral, RecoverRewardOnly, RecoverSkip} {
+		parsed, err := ParseRecoverPolicy(string(policy))
+		if err != nil || parsed != policy {
+			t.Fatalf("parsed %q as %q, %v", policy, parsed, err)
+		}
+	}
+
+	_, err := ParseRecoverPolicy("penalize")
+	if err == nil {
+		t.Fatal("unknown policy is accepted")
+	}
+}
+
+func TestRecoverRewardOnly(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.RecoverPolicy = RecoverRewardOnly
+	v, db := newTestValidator(t, cfg)
+	stopped := stopAt(t, v, db, 5)
+
+	err := v.Recover(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cycles := listCycles(t, db)
+	recovered := cycles[1:]
+	if len(recovered) < 4 {
+		t.Fatalf("%d cycles recovered, expect at least 4 after %d", len(recovered), stopped)
+	}
+	for i, cycle := range recovered {
+		if cycle.ID != stopped+int64(i)+1 || cycle.Status != store.CycleRecovered || cycle.Policy != string(RecoverRewardOnly) {
+			t.Fatalf("recovered cycle %+v", cycle)
+		}
+	}
+
+	// every node is settled in each cycle, without penalty
+	if n := len(listLedger(t, db)); n != 2*len(recovered) {
+		t.Fatalf("%d ledger entries, expect %d", n, 2*len(recovered))
+	}
+	paid := checkProfit(t, db, paidNode.Provider, 10000, 0, 0)
+	last := recovered[len(recovered)-1]
+	if paid.LastTime.Unix() != last.ID*v.cycleSeconds() {
+		t.Fatalf("profit is settled up to %d, expect the start of cycle %d", paid.LastTime.Unix(), last.ID)
+	}
+	checkProfit(t, db, pendingNode.Provider, 0, 10000, 0)
+
+	// nothing is missed anymore
+	err = v.Recover(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(listCycles(t, db)); n != len(cycles) {
+		t.Fatalf("%d cycles after recovering again, expect %d", n, len(cycles))
+	}
+}
+
+func TestRecoverSkip(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.RecoverPolicy = RecoverSkip
+	cfg.MaxRecoverCycles = 2
+	v, db := newTestValidator(t, cfg)
+	stopAt(t, v, db, 5)
+
+	err := v.Recover(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only the latest cycles are recorded, profits are untouched
+	cycles := listCycles(t, db)
+	if len(cycles) != 3 {
+		t.Fatalf("%d cycles, expect the stopped one and 2 recovered", len(cycles))
+	}
+	for _, cycle := range cycles[1:] {
+		if cycle.Status != store.CycleSkipped {
+			t.Fatalf("recovered cycle %+v", cycle)
+		}
+	}
+	if n := len(listLedger(t, db)); n != 0 {
+		t.Fatalf("%d ledger entries, expect none", n)
+	}
+	checkProfit(t, db, paidNode.Provider, 0, 10000, 0)
+}
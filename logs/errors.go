@@ -1,6 +1,7 @@
 package logs
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 )
@@ -124,6 +125,70 @@ func (e WalletError) Error() string {
 	return e.Message
 }
 
+type BadRequest struct {
+	Message string
+}
+
+func (e BadRequest) Error() string {
+	return e.Message
+}
+
+type NotFound struct {
+	Message string
+}
+
+func (e NotFound) Error() string {
+	return e.Message
+}
+
+type Unavailable struct {
+	Message string
+}
+
+func (e Unavailable) Error() string {
+	return e.Message
+}
+
+type RateLimited struct {
+	Message string
+}
+
+func (e RateLimited) Error() string {
+	return e.Message
+}
+
+type ProofOutsideWindow struct {
+	Message string
+}
+
+func (e ProofOutsideWindow) Error() string {
+	return e.Message
+}
+
+type ProofBadPOW struct {
+	Message string
+}
+
+func (e ProofBadPOW) Error() string {
+	return e.Message
+}
+
+type ProofNotChallenged struct {
+	Message string
+}
+
+func (e ProofNotChallenged) Error() string {
+	return e.Message
+}
+
+type ProofDuplicate struct {
+	Message string
+}
+
+func (e ProofDuplicate) Error() string {
+	return e.Message
+}
+
 type APIError struct {
 	Code           string
 	Description    string
@@ -152,6 +217,14 @@ const (
 	ErrController
 	ErrNoPermission
 	ErrWallet
+	ErrBadRequest
+	ErrUnavailable
+	ErrRateLimited
+	ErrProofOutsideWindow
+	ErrProofBadPOW
+	ErrProofNotChallenged
+	ErrProofDuplicate
+	ErrNotFound
 )
 
 func (e errorCodeMap) ToAPIErrWithErr(errCode APIErrorCode, err error) APIError {
@@ -250,49 +323,101 @@ var ErrorCodes = errorCodeMap{
 		Description:    "datastore error",
 		HTTPStatusCode: 528,
 	},
+	ErrBadRequest: {
+		Code:           "BadRequest",
+		Description:    "Invalid request",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrUnavailable: {
+		Code:           "Unavailable",
+		Description:    "Service busy, please retry later",
+		HTTPStatusCode: http.StatusServiceUnavailable,
+	},
+	ErrRateLimited: {
+		Code:           "RateLimited",
+		Description:    "Too many requests, please slow down",
+		HTTPStatusCode: http.StatusTooManyRequests,
+	},
+	ErrProofOutsideWindow: {
+		Code:           "ProofOutsideWindow",
+		Description:    "Proof is not submitted within the prove period",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrProofBadPOW: {
+		Code:           "ProofBadPOW",
+		Description:    "Proof does not meet the difficulty",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrProofNotChallenged: {
+		Code:           "ProofNotChallenged",
+		Description:    "Node is not challenged in current cycle",
+		HTTPStatusCode: http.StatusBadRequest,
+	},
+	ErrProofDuplicate: {
+		Code:           "ProofDuplicate",
+		Description:    "Proof of the node is already accepted in current cycle",
+		HTTPStatusCode: http.StatusConflict,
+	},
+	ErrNotFound: {
+		Code:           "NotFound",
+		Description:    "The requested record does not exist",
+		HTTPStatusCode: http.StatusNotFound,
+	},
 }
 
+// the typed error found in the chain of err
+func as[T error](err error) (error, bool) {
+	var typed T
+	if errors.As(err, &typed) {
+		return typed, true
+	}
+	return nil, false
+}
+
+// codes of the typed errors returned to clients
+var apiErrorTypes = []struct {
+	code APIErrorCode
+	as   func(error) (error, bool)
+}{
+	{ErrNotImplemented, as[NotImplemented]},
+	{ErrStorage, as[StorageError]},
+	{ErrAddress, as[AddressError]},
+	{ErrStorageNotSupport, as[StorageNotSupport]},
+	{ErrAuthenticationFailed, as[AuthenticationFailed]},
+	{ErrContract, as[ContractError]},
+	{ErrEth, as[EthError]},
+	{ErrServer, as[ServerError]},
+	{ErrGateway, as[GatewayError]},
+	{ErrConfig, as[ConfigError]},
+	{ErrDataBase, as[DataBaseError]},
+	{ErrController, as[ControllerError]},
+	{ErrNoPermission, as[NoPermission]},
+	{ErrWallet, as[WalletError]},
+	{ErrDataStore, as[*DataStoreError]},
+	{ErrBadRequest, as[BadRequest]},
+	{ErrUnavailable, as[Unavailable]},
+	{ErrRateLimited, as[RateLimited]},
+	{ErrProofOutsideWindow, as[ProofOutsideWindow]},
+	{ErrProofBadPOW, as[ProofBadPOW]},
+	{ErrProofNotChallenged, as[ProofNotChallenged]},
+	{ErrProofDuplicate, as[ProofDuplicate]},
+	{ErrNotFound, as[NotFound]},
+}
+
+// the typed error in the chain of err decides the code, and only its message is returned,
+// any other error is an internal error without its text
 func ToAPIErrorCode(err error) APIError {
 	if err == nil {
 		return ErrorCodes.ToAPIErr(ErrNone)
 	}
-	var apiErr APIErrorCode
-
-	switch err.(type) {
-	case NotImplemented:
-		apiErr = ErrNotImplemented
-	case StorageError:
-		apiErr = ErrStorage
-	case AddressError:
-		apiErr = ErrAddress
-	case StorageNotSupport:
-		apiErr = ErrStorageNotSupport
-	case AuthenticationFailed:
-		apiErr = ErrAuthenticationFailed
-	case ContractError:
-		apiErr = ErrContract
-	case EthError:
-		apiErr = ErrEth
-	case ServerError:
-		apiErr = ErrServer
-	case GatewayError:
-		apiErr = ErrGateway
-	case ConfigError:
-		apiErr = ErrConfig
-	case DataBaseError:
-		apiErr = ErrDataBase
-	case ControllerError:
-		apiErr = ErrController
-	case NoPermission:
-		apiErr = ErrNoPermission
-	case WalletError:
-		apiErr = ErrWallet
-	case *DataStoreError:
-		apiErr = ErrDataStore
-	default:
-		apiErr = ErrInternal
+
+	for _, t := range apiErrorTypes {
+		typed, ok := t.as(err)
+		if ok {
+			return ErrorCodes.ToAPIErrWithErr(t.code, typed)
+		}
 	}
-	return ErrorCodes.ToAPIErrWithErr(apiErr, err)
+	return ErrorCodes.ToAPIErr(ErrInternal)
 }
 
 var (
@@ -304,3 +429,10 @@ type ErrResponse struct {
 	Package string
 	Err     error
 }
+
+// error body returned by the http api
+type APIErrorResponse struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+	RequestID   string `json:"request_id"`
+}
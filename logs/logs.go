@@ -3,53 +3,102 @@ package logs
 import (
 	"fmt"
 	"os"
+	"strings"
 	"sync"
+	"sync/atomic"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
-	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-var mLogger *zap.SugaredLogger
-var mLoglevel zap.AtomicLevel
+var mLoglevel = zap.NewAtomicLevel()
 var lk sync.Mutex
 
+// levels of named loggers, overriding mLoglevel
+var mNamedLevels atomic.Pointer[map[string]zapcore.Level]
+
+// names of the loggers handed out
+var mNames = make(map[string]struct{})
+
+// sinks shared by all loggers, stdout in json until Init
+var mCore atomic.Pointer[zapcore.Core]
+var defaultCore = zapcore.NewCore(mustEncoder(FormatJSON), zapcore.Lock(os.Stdout), zapcore.DebugLevel)
+
+// output format
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
 func Logger(name string) *zap.SugaredLogger {
 	lk.Lock()
 	defer lk.Unlock()
-	return mLogger.Named(name)
+
+	mNames[name] = struct{}{}
+	return zap.New(&namedCore{name: name}, zap.AddCaller()).Named(name).Sugar()
 }
 
-// StartLogger starts
-func init() {
-	mLoglevel = zap.NewAtomicLevel()
+// core of a named logger, its level and the shared core are looked up on each entry
+// so runtime changes reach loggers handed out before
+type namedCore struct {
+	name   string
+	fields []zapcore.Field
+}
 
-	outputs := []string{"stdout"}
-	debugWriter, _, err := zap.Open(outputs...)
-	if err != nil {
-		panic(fmt.Sprintf("unable to open logging output: %v", err))
-	}
+func (c *namedCore) Enabled(l zapcore.Level) bool {
+	return l >= levelOf(c.name)
+}
 
-	lf := os.Getenv("MEFS_LOG_FILE")
-	if lf != "" {
-		debugWriter = getLogWriter(lf)
+func (c *namedCore) With(fields []zapcore.Field) zapcore.Core {
+	return &namedCore{
+		name:   c.name,
+		fields: append(c.fields[:len(c.fields):len(c.fields)], fields...),
 	}
+}
 
-	encoder := getEncoder()
+func (c *namedCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
 
-	core := zapcore.NewCore(encoder, debugWriter, mLoglevel)
+func (c *namedCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if len(c.fields) > 0 {
+		fields = append(c.fields[:len(c.fields):len(c.fields)], fields...)
+	}
+	return sharedCore().Write(ent, fields)
+}
 
-	// NewProduction
-	logger := zap.New(core, zap.AddCaller())
+func (c *namedCore) Sync() error {
+	return sharedCore().Sync()
+}
 
-	mLogger = logger.Sugar()
+func sharedCore() zapcore.Core {
+	core := mCore.Load()
+	if core == nil {
+		return defaultCore
+	}
+	return *core
+}
 
-	l := getLogLevel(os.Getenv("MEFS_LOG_LEVEL"))
+func namedLevels() map[string]zapcore.Level {
+	levels := mNamedLevels.Load()
+	if levels == nil {
+		return nil
+	}
+	return *levels
+}
 
-	mLoglevel.SetLevel(l)
+func levelOf(name string) zapcore.Level {
+	l, ok := namedLevels()[name]
+	if ok {
+		return l
+	}
+	return mLoglevel.Level()
 }
 
-func getEncoder() zapcore.Encoder {
+func getEncoder(format string) (zapcore.Encoder, error) {
 	encoderConfig := zapcore.EncoderConfig{
 		TimeKey:        "time",
 		LevelKey:       "level",
@@ -64,49 +113,138 @@ func getEncoder() zapcore.Encoder {
 		EncodeCaller:   zapcore.ShortCallerEncoder,
 	}
 
-	return zapcore.NewJSONEncoder(encoderConfig)
+	switch format {
+	case FormatJSON, "":
+		return zapcore.NewJSONEncoder(encoderConfig), nil
+	case FormatConsole:
+		encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+		return zapcore.NewConsoleEncoder(encoderConfig), nil
+	}
+
+	return nil, fmt.Errorf("unknown log format %q, expect json or console", format)
 }
 
-func getLogWriter(filename string) zapcore.WriteSyncer {
-	lumberJackLogger := &lumberjack.Logger{
-		Filename:   filename + ".log",
-		MaxSize:    100, //MB
-		MaxBackups: 3,
-		MaxAge:     30, //days
-		Compress:   false,
+func mustEncoder(format string) zapcore.Encoder {
+	encoder, err := getEncoder(format)
+	if err != nil {
+		panic(err)
+	}
+	return encoder
+}
+
+// level of loggers without their own level
+func SetLogLevel(level string) error {
+	var l zapcore.Level
+	err := l.UnmarshalText([]byte(level))
+	if err != nil {
+		return err
 	}
-	return zapcore.AddSync(lumberJackLogger)
+
+	lk.Lock()
+	defer lk.Unlock()
+
+	mLoglevel.SetLevel(l)
+	return nil
 }
 
-func getLogLevel(level string) zapcore.Level {
-	l := zapcore.InfoLevel
-	switch level {
-	case "debug", "DEBUG":
-		l = zapcore.DebugLevel
-	case "info", "INFO", "": // make the zero value useful
-		l = zapcore.InfoLevel
-	case "warn", "WARN":
-		l = zapcore.WarnLevel
-	case "error", "ERROR":
-		l = zapcore.ErrorLevel
-	case "dpanic", "DPANIC":
-		l = zapcore.DPanicLevel
-	case "panic", "PANIC":
-		l = zapcore.PanicLevel
-	case "fatal", "FATAL":
-		l = zapcore.FatalLevel
+func GetLogLevel() string {
+	return mLoglevel.Level().String()
+}
 
+// level of the named logger, an empty level makes it follow the global level again
+func SetNamedLogLevel(name, level string) error {
+	lk.Lock()
+	defer lk.Unlock()
+
+	levels := copyNamedLevels()
+	if level == "" {
+		delete(levels, name)
+	} else {
+		var l zapcore.Level
+		err := l.UnmarshalText([]byte(level))
+		if err != nil {
+			return err
+		}
+		levels[name] = l
 	}
 
-	return l
+	mNamedLevels.Store(&levels)
+	return nil
 }
 
-func SetLogLevel(level string) error {
-	l := getLogLevel(level)
+// effective level of every logger handed out or configured
+func GetNamedLogLevels() map[string]string {
+	lk.Lock()
+	defer lk.Unlock()
+
+	res := make(map[string]string)
+	for name := range mNames {
+		res[name] = levelOf(name).String()
+	}
+	for name, l := range namedLevels() {
+		res[name] = l.String()
+	}
+	return res
+}
+
+// apply a level spec like "info,gin=warn,grid validator=debug", a bare level is the global level.
+// named levels not in the spec are cleared
+func ApplyLevels(spec string) error {
+	global, named, err := ParseLevels(spec)
+	if err != nil {
+		return err
+	}
 
 	lk.Lock()
 	defer lk.Unlock()
 
-	mLoglevel.SetLevel(l)
+	if global != nil {
+		mLoglevel.SetLevel(*global)
+	}
+	mNamedLevels.Store(&named)
 	return nil
 }
+
+// parse a level spec, entries are separated by commas or new lines
+func ParseLevels(spec string) (*zapcore.Level, map[string]zapcore.Level, error) {
+	var global *zapcore.Level
+	named := make(map[string]zapcore.Level)
+
+	entries := strings.FieldsFunc(spec, func(r rune) bool {
+		return r == ',' || r == '\n'
+	})
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		name, level, ok := strings.Cut(entry, "=")
+		if !ok {
+			name, level = "", entry
+		}
+
+		var l zapcore.Level
+		err := l.UnmarshalText([]byte(strings.TrimSpace(level)))
+		if err != nil {
+			return nil, nil, fmt.Errorf("log level of %q: %w", entry, err)
+		}
+
+		name = strings.TrimSpace(name)
+		if name == "" {
+			global = &l
+			continue
+		}
+		named[name] = l
+	}
+
+	return global, named, nil
+}
+
+func copyNamedLevels() map[string]zapcore.Level {
+	levels := make(map[string]zapcore.Level)
+	for name, l := range namedLevels() {
+		levels[name] = l
+	}
+	return levels
+}
@@ -0,0 +1,274 @@
+package logs
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+type Config struct {
+	// json or console
+	Format string
+	// write to stdout, besides the file and syslog
+	Stdout bool
+	File   FileConfig
+	Syslog SyslogConfig
+	// sampling of the loggers returned by Sampled
+	Sampling SamplingConfig
+}
+
+// rotated log file, disabled without a path
+type FileConfig struct {
+	// .log is added when the path has no extension
+	Path string
+	// size in megabytes before it is rotated
+	MaxSize int
+	// rotated files to keep, 0 keeps all
+	MaxBackups int
+	// days to keep rotated files, 0 keeps them regardless of age
+	MaxAge   int
+	Compress bool
+}
+
+// syslog over udp, disabled without an address
+type SyslogConfig struct {
+	// host:port of the syslog server
+	Address string
+	Tag     string
+	// facility name, e.g.(daemon, local0)
+	Facility string
+}
+
+// log the first entries of a message in each tick, then every Thereafter-th of them.
+// sampling is off when First is 0
+type SamplingConfig struct {
+	Tick       time.Duration
+	First      int
+	Thereafter int
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Format: FormatJSON,
+		Stdout: true,
+		File: FileConfig{
+			MaxSize:    100,
+			MaxBackups: 3,
+			MaxAge:     30,
+		},
+		Syslog: SyslogConfig{
+			Tag:      "grid-validator",
+			Facility: "local0",
+		},
+		Sampling: SamplingConfig{
+			Tick:       time.Second,
+			First:      10,
+			Thereafter: 100,
+		},
+	}
+}
+
+// open the sinks of cfg and send all loggers to them, loggers write to stdout until then.
+// the returned func flushes and closes the sinks
+func Init(cfg Config) (func() error, error) {
+	encoder, err := getEncoder(cfg.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	var cores []zapcore.Core
+	var closers []func() error
+	closeAll := func() error {
+		var errs []error
+		for _, close := range closers {
+			errs = append(errs, close())
+		}
+		return errors.Join(errs...)
+	}
+
+	// filtered by the named cores
+	if cfg.Stdout {
+		cores = append(cores, zapcore.NewCore(encoder, zapcore.Lock(os.Stdout), zapcore.DebugLevel))
+	}
+
+	if cfg.File.Path != "" {
+		file := newFileWriter(cfg.File)
+		cores = append(cores, zapcore.NewCore(encoder.Clone(), zapcore.AddSync(file), zapcore.DebugLevel))
+		closers = append(closers, file.Close)
+	}
+
+	if cfg.Syslog.Address != "" {
+		core, err := newSyslogCore(encoder.Clone(), cfg.Syslog)
+		if err != nil {
+			_ = closeAll()
+			return nil, err
+		}
+		cores = append(cores, core)
+		closers = append(closers, core.conn.Close)
+	}
+
+	if len(cores) == 0 {
+		return nil, errors.New("no log output, enable stdout, a file or syslog")
+	}
+
+	if cfg.Sampling.First > 0 {
+		mSampler.Store(newSampler(cfg.Sampling))
+	} else {
+		mSampler.Store(nil)
+	}
+
+	core := zapcore.NewTee(cores...)
+	mCore.Store(&core)
+
+	return func() error {
+		err := core.Sync()
+		mCore.Store(nil)
+		return errors.Join(err, closeAll())
+	}, nil
+}
+
+func newFileWriter(cfg FileConfig) *lumberjack.Logger {
+	path := cfg.Path
+	if filepath.Ext(path) == "" {
+		path += ".log"
+	}
+
+	return &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    cfg.MaxSize,
+		MaxBackups: cfg.MaxBackups,
+		MaxAge:     cfg.MaxAge,
+		Compress:   cfg.Compress,
+	}
+}
+
+// facility codes of rfc 5424
+var facilities = map[string]int{
+	"kern":   0,
+	"user":   1,
+	"daemon": 3,
+	"auth":   4,
+	"syslog": 5,
+	"local0": 16,
+	"local1": 17,
+	"local2": 18,
+	"local3": 19,
+	"local4": 20,
+	"local5": 21,
+	"local6": 22,
+	"local7": 23,
+}
+
+// severities of rfc 5424
+const (
+	severityCrit    = 2
+	severityErr     = 3
+	severityWarning = 4
+	severityInfo    = 6
+	severityDebug   = 7
+)
+
+// sends each entry as one rfc 5424 datagram with the severity of its level,
+// written directly over udp since log/syslog does not build on windows
+type syslogCore struct {
+	encoder  zapcore.Encoder
+	conn     net.Conn
+	facility int
+	hostname string
+	tag      string
+}
+
+func newSyslogCore(encoder zapcore.Encoder, cfg SyslogConfig) (*syslogCore, error) {
+	facility, ok := facilities[strings.ToLower(cfg.Facility)]
+	if !ok {
+		return nil, fmt.Errorf("unknown syslog facility %q", cfg.Facility)
+	}
+
+	// udp is connectionless, so this only fails on a bad address
+	conn, err := net.Dial("udp", cfg.Address)
+	if err != nil {
+		return nil, fmt.Errorf("syslog %s: %w", cfg.Address, err)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	tag := cfg.Tag
+	if tag == "" {
+		tag = filepath.Base(os.Args[0])
+	}
+
+	return &syslogCore{
+		encoder:  encoder,
+		conn:     conn,
+		facility: facility,
+		hostname: hostname,
+		tag:      tag,
+	}, nil
+}
+
+func (c *syslogCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (c *syslogCore) With(fields []zapcore.Field) zapcore.Core {
+	encoder := c.encoder.Clone()
+	for _, field := range fields {
+		field.AddTo(encoder)
+	}
+	return &syslogCore{
+		encoder:  encoder,
+		conn:     c.conn,
+		facility: c.facility,
+		hostname: c.hostname,
+		tag:      c.tag,
+	}
+}
+
+func (c *syslogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, c)
+}
+
+func (c *syslogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.encoder.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+
+	severity := severityDebug
+	switch {
+	case ent.Level >= zapcore.DPanicLevel:
+		severity = severityCrit
+	case ent.Level >= zapcore.ErrorLevel:
+		severity = severityErr
+	case ent.Level >= zapcore.WarnLevel:
+		severity = severityWarning
+	case ent.Level >= zapcore.InfoLevel:
+		severity = severityInfo
+	}
+
+	// <pri>version timestamp hostname app-name procid msgid structured-data msg
+	msg := fmt.Sprintf("<%d>1 %s %s %s %d - - %s",
+		c.facility*8+severity,
+		ent.Time.Format(time.RFC3339Nano),
+		c.hostname,
+		c.tag,
+		os.Getpid(),
+		strings.TrimSuffix(buf.String(), "\n"),
+	)
+	_, err = c.conn.Write([]byte(msg))
+	return err
+}
+
+func (c *syslogCore) Sync() error {
+	return nil
+}
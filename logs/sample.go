@@ -0,0 +1,79 @@
+package logs
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// sampler of the loggers returned by Sampled, nil logs every entry
+var mSampler atomic.Pointer[sampler]
+
+// counts entries by logger, level and message in the current tick
+type sampler struct {
+	cfg SamplingConfig
+
+	lk     sync.Mutex
+	reset  time.Time
+	counts map[string]int
+}
+
+func newSampler(cfg SamplingConfig) *sampler {
+	if cfg.Tick <= 0 {
+		cfg.Tick = time.Second
+	}
+	return &sampler{
+		cfg:    cfg,
+		counts: make(map[string]int),
+	}
+}
+
+func (s *sampler) allow(ent zapcore.Entry) bool {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	if ent.Time.After(s.reset) {
+		s.reset = ent.Time.Add(s.cfg.Tick)
+		clear(s.counts)
+	}
+
+	key := ent.LoggerName + "|" + ent.Level.String() + "|" + ent.Message
+	n := s.counts[key]
+	s.counts[key] = n + 1
+
+	if n < s.cfg.First {
+		return true
+	}
+	return s.cfg.Thereafter > 0 && (n-s.cfg.First)%s.cfg.Thereafter == s.cfg.Thereafter-1
+}
+
+// logger for high-volume messages, repeats of a message are sampled as configured in Init.
+// messages should be constant, with the details in fields
+func Sampled(log *zap.SugaredLogger) *zap.SugaredLogger {
+	return log.Desugar().WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return sampledCore{core}
+	})).Sugar()
+}
+
+type sampledCore struct {
+	zapcore.Core
+}
+
+func (c sampledCore) With(fields []zapcore.Field) zapcore.Core {
+	return sampledCore{c.Core.With(fields)}
+}
+
+func (c sampledCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+
+	s := mSampler.Load()
+	if s != nil && !s.allow(ent) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}